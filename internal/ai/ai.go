@@ -0,0 +1,105 @@
+package ai
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/battle"
+	"github.com/rashadat1/goPokedex/internal/damageCalculator"
+)
+
+// Strategy decides what a computer controlled side does every turn and who it sends in when its
+// pokemon faints or is switched out by a move
+type Strategy interface {
+	ChooseAction(b *battle.Battle, side battle.Side) battle.Action
+	ChooseReplacement(b *battle.Battle, side battle.Side) int
+}
+
+var Names = []string{"random", "greedy", "lookahead"}
+
+// New returns the strategy with the given name. Strategies draw from their own rng so the choices
+// they make don't change the battle's rolls
+func New(name string, rng *rand.Rand) (Strategy, error) {
+	switch name {
+	case "random":
+		return &Random{Rng: rng}, nil
+	case "greedy":
+		return &Greedy{Rng: rng}, nil
+	case "lookahead":
+		return &Lookahead{}, nil
+	}
+	return nil, fmt.Errorf("unknown ai strategy %q, expected one of %s", name, strings.Join(Names, ", "))
+}
+
+// LegalMoves lists the move slots the pokemon can pick, every known move with pp left. It is empty
+// once they have all run out (or a saved pokemon knows none) and the side has to pass
+func LegalMoves(pokemon *api.Pokemon) []int {
+	withPP := []int{}
+	for i, moveInst := range pokemon.Moves {
+		if moveInst != nil && moveInst.RemainingPP > 0 {
+			withPP = append(withPP, i)
+		}
+	}
+	return withPP
+}
+
+// Random picks any legal move and sends in any usable pokemon
+type Random struct {
+	Rng            *rand.Rand
+}
+
+func (r *Random) ChooseAction(b *battle.Battle, side battle.Side) battle.Action {
+	moves := LegalMoves(b.Pokemon[side])
+	if len(moves) == 0 {
+		return battle.Pass()
+	}
+	return battle.Fight(moves[r.Rng.Intn(len(moves))])
+}
+func (r *Random) ChooseReplacement(b *battle.Battle, side battle.Side) int {
+	usable := b.Parties[side].Usable()
+	if len(usable) == 0 {
+		return -1
+	}
+	return usable[r.Rng.Intn(len(usable))]
+}
+
+// Greedy always picks the move with the highest expected damage against the pokemon in front of it
+// and sends in whoever hits that pokemon hardest. With nothing but status moves it picks at random
+type Greedy struct {
+	Rng            *rand.Rand
+}
+
+func (g *Greedy) ChooseAction(b *battle.Battle, side battle.Side) battle.Action {
+	slot, damage := bestMove(b, b.Pokemon[side], b.Pokemon[side.Opponent()])
+	if slot < 0 {
+		return battle.Pass()
+	}
+	if damage == 0 {
+		moves := LegalMoves(b.Pokemon[side])
+		return battle.Fight(moves[g.Rng.Intn(len(moves))])
+	}
+	return battle.Fight(slot)
+}
+func (g *Greedy) ChooseReplacement(b *battle.Battle, side battle.Side) int {
+	best, bestDamage := -1, -1.0
+	for _, slot := range b.Parties[side].Usable() {
+		if _, damage := bestMove(b, b.Parties[side].Members[slot], b.Pokemon[side.Opponent()]); damage > bestDamage {
+			best, bestDamage = slot, damage
+		}
+	}
+	return best
+}
+
+// bestMove is the legal move of the attacker with the highest expected damage against the defender
+func bestMove(b *battle.Battle, attacker, defender *api.Pokemon) (int, float64) {
+	best, bestDamage := -1, 0.0
+	for _, slot := range LegalMoves(attacker) {
+		damage := damageCalculator.ExpectedDamage(attacker, defender, attacker.Moves[slot], b.Context)
+		if best == -1 || damage > bestDamage {
+			best, bestDamage = slot, damage
+		}
+	}
+	return best, bestDamage
+}
@@ -0,0 +1,106 @@
+package ai
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/battle"
+	"github.com/rashadat1/goPokedex/internal/fixtures"
+)
+
+func testTypeChart() *api.TypeEffect {
+	return &api.TypeEffect{
+		TypeMap: map[string]api.Relations{
+			"normal": {Effectiveness: map[string]float32{"ghost": 0}},
+			"fire": {Effectiveness: map[string]float32{"grass": 2}},
+		},
+	}
+}
+func testBattle(user, opponent []*api.Pokemon) *battle.Battle {
+	userParty, _ := battle.NewParty(user...)
+	oppParty, _ := battle.NewParty(opponent...)
+	return battle.NewTeamBattle(userParty, oppParty, testTypeChart(), rand.New(rand.NewSource(1)))
+}
+
+func TestRandomOnlyPicksLegalMoves(t *testing.T) {
+	tackle := fixtures.Move("tackle", "normal", "physical", 40, 100)
+	ember := fixtures.Move("ember", "fire", "physical", 40, 100)
+	user := fixtures.Pokemon("charmander", []string{"fire"}, 100, 50, tackle, nil, ember)
+	user.Moves[0].RemainingPP = 0
+	currentBattle := testBattle([]*api.Pokemon{user}, []*api.Pokemon{fixtures.Pokemon("oddish", []string{"grass"}, 100, 50, tackle)})
+
+	strategy := &Random{Rng: rand.New(rand.NewSource(1))}
+	for i := 0; i < 20; i++ {
+		if action := strategy.ChooseAction(currentBattle, battle.SideUser); action.MoveIndex != 2 {
+			t.Fatalf("Got move slot %d expected the only move with pp left (2)", action.MoveIndex)
+		}
+	}
+}
+
+func TestGreedyPicksHighestExpectedDamage(t *testing.T) {
+	tackle := fixtures.Move("tackle", "normal", "physical", 50, 100)
+	ember := fixtures.Move("ember", "fire", "physical", 40, 100)
+	user := fixtures.Pokemon("charmander", []string{"fire"}, 100, 50, tackle, ember)
+	bench := fixtures.Pokemon("rattata", []string{"normal"}, 100, 50, tackle)
+	bench2 := fixtures.Pokemon("vulpix", []string{"fire"}, 100, 50, ember)
+	currentBattle := testBattle([]*api.Pokemon{user, bench, bench2}, []*api.Pokemon{fixtures.Pokemon("oddish", []string{"grass"}, 100, 50, tackle)})
+
+	strategy := &Greedy{Rng: rand.New(rand.NewSource(1))}
+	if action := strategy.ChooseAction(currentBattle, battle.SideUser); action.Kind != battle.ActionFight || action.MoveIndex != 1 {
+		t.Errorf("Got %+v expected ember against a grass type", action)
+	}
+	if slot := strategy.ChooseReplacement(currentBattle, battle.SideUser); slot != 2 {
+		t.Errorf("Got slot %d expected the fire type vulpix (2)", slot)
+	}
+}
+
+func TestLookahead(t *testing.T) {
+	tackle := fixtures.Move("tackle", "normal", "physical", 80, 100)
+	shadowBall := fixtures.Move("shadow-ball", "ghost", "physical", 80, 100)
+
+	// staying in means getting knocked out before moving, the ghost on the bench takes nothing
+	rattata := fixtures.Pokemon("rattata", []string{"normal"}, 10, 10, tackle)
+	gengar := fixtures.Pokemon("gengar", []string{"ghost"}, 300, 50, shadowBall)
+	currentBattle := testBattle([]*api.Pokemon{rattata, gengar}, []*api.Pokemon{fixtures.Pokemon("tauros", []string{"normal"}, 500, 100, tackle)})
+	if action := (&Lookahead{}).ChooseAction(currentBattle, battle.SideUser); action.Kind != battle.ActionSwitch || action.Slot != 1 {
+		t.Errorf("Got %+v expected a switch to gengar", action)
+	}
+
+	// the slower pokemon only gets its knock out in first with the priority move
+	quickAttack := fixtures.Move("quick-attack", "normal", "physical", 40, 100)
+	quickAttack.Priority = 1
+	megaKick := fixtures.Move("mega-kick", "normal", "physical", 150, 100)
+	raticate := fixtures.Pokemon("raticate", []string{"normal"}, 10, 10, megaKick, quickAttack)
+	currentBattle = testBattle([]*api.Pokemon{raticate}, []*api.Pokemon{fixtures.Pokemon("tauros", []string{"normal"}, 10, 100, tackle)})
+	if action := (&Lookahead{}).ChooseAction(currentBattle, battle.SideUser); action.Kind != battle.ActionFight || action.MoveIndex != 1 {
+		t.Errorf("Got %+v expected quick-attack to knock the faster foe out first", action)
+	}
+}
+
+func TestNoLegalMovesPasses(t *testing.T) {
+	tackle := fixtures.Move("tackle", "normal", "physical", 40, 100)
+	foe := fixtures.Pokemon("rattata", []string{"normal"}, 100, 50, tackle)
+	emptied := fixtures.Pokemon("pidgey", []string{"normal"}, 100, 50, tackle)
+	emptied.Moves[0].RemainingPP = 0
+	noMoves := fixtures.Pokemon("ditto", []string{"normal"}, 100, 50)
+	strategies := []Strategy{&Random{Rng: rand.New(rand.NewSource(1))}, &Greedy{Rng: rand.New(rand.NewSource(1))}, &Lookahead{}}
+	for _, pokemon := range []*api.Pokemon{emptied, noMoves} {
+		for _, strategy := range strategies {
+			currentBattle := testBattle([]*api.Pokemon{foe}, []*api.Pokemon{pokemon})
+			action := strategy.ChooseAction(currentBattle, battle.SideOpponent)
+			if action.Kind != battle.ActionPass {
+				t.Errorf("%T with %s: Got %+v expected a pass", strategy, pokemon.Species, action)
+			}
+			// the pass plays out as a turn where only the foe moves
+			events := currentBattle.PlayTurn([2]battle.Action{battle.Fight(0), action})
+			passed := false
+			for _, event := range events {
+				passed = passed || event.Kind == battle.EventPass
+			}
+			if !passed {
+				t.Errorf("%T with %s: Got %v expected a pass event", strategy, pokemon.Species, events)
+			}
+		}
+	}
+}
@@ -0,0 +1,116 @@
+package ai
+
+import (
+	"math"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/battle"
+	"github.com/rashadat1/goPokedex/internal/damageCalculator"
+)
+
+// a knock out is worth this much on top of the hp it takes
+const knockOutBonus = 1.0
+
+// a pokemon switched in only attacks the turn after, so what it threatens counts for less
+const switchThreatDiscount = 0.5
+
+// Lookahead looks one turn ahead. It assumes the foe answers with its highest damage move, plays
+// every move and every switch against that answer in speed order and scores the turn as the share
+// of hp it takes minus the share it loses, with knock outs worth extra
+type Lookahead struct{}
+
+func (l *Lookahead) ChooseAction(b *battle.Battle, side battle.Side) battle.Action {
+	self, foe := b.Pokemon[side], b.Pokemon[side.Opponent()]
+	foeMove := greedyMove(b, foe, self)
+
+	// with no move to use the side passes unless a switch scores better
+	best := battle.Pass()
+	bestScore := math.Inf(-1)
+	for _, slot := range LegalMoves(self) {
+		if score := scoreFight(b, self, foe, self.Moves[slot], foeMove); score > bestScore {
+			best, bestScore = battle.Fight(slot), score
+		}
+	}
+	if !b.Context.PokemonStates[self].CanFlee {
+		return best
+	}
+	for _, slot := range b.Parties[side].Usable() {
+		if score := scoreSwitch(b, b.Parties[side].Members[slot], foe, foeMove); score > bestScore {
+			best, bestScore = battle.Switch(slot), score
+		}
+	}
+	return best
+}
+
+// ChooseReplacement sends in the pokemon with the best matchup, knowing the foe picks its move
+// against whoever comes in
+func (l *Lookahead) ChooseReplacement(b *battle.Battle, side battle.Side) int {
+	foe := b.Pokemon[side.Opponent()]
+	best, bestScore := -1, math.Inf(-1)
+	for _, slot := range b.Parties[side].Usable() {
+		incoming := b.Parties[side].Members[slot]
+		if score := scoreSwitch(b, incoming, foe, greedyMove(b, foe, incoming)); score > bestScore {
+			best, bestScore = slot, score
+		}
+	}
+	return best
+}
+
+func greedyMove(b *battle.Battle, attacker, defender *api.Pokemon) *api.MoveInstance {
+	slot, _ := bestMove(b, attacker, defender)
+	if slot < 0 {
+		return nil
+	}
+	return attacker.Moves[slot]
+}
+
+// scoreFight plays out one exchange: whoever moves first and knocks the other out takes no hit back
+func scoreFight(b *battle.Battle, self, foe *api.Pokemon, move, foeMove *api.MoveInstance) float64 {
+	dealt := damageCalculator.ExpectedDamage(self, foe, move, b.Context)
+	taken := 0.0
+	if foeMove != nil {
+		taken = damageCalculator.ExpectedDamage(foe, self, foeMove, b.Context)
+	}
+	if movesFirst(b, self, move, foe, foeMove) {
+		if dealt >= float64(foe.CurrHp) {
+			taken = 0
+		}
+	} else if taken >= float64(self.CurrHp) {
+		dealt = 0
+	}
+	return hpShare(dealt, foe) - hpShare(taken, self)
+}
+
+// scoreSwitch is the incoming pokemon taking the foe's move this turn, weighed against what it
+// threatens to do from next turn on
+func scoreSwitch(b *battle.Battle, incoming, foe *api.Pokemon, foeMove *api.MoveInstance) float64 {
+	taken := 0.0
+	if foeMove != nil {
+		taken = damageCalculator.ExpectedDamage(foe, incoming, foeMove, b.Context)
+	}
+	_, threat := bestMove(b, incoming, foe)
+	return switchThreatDiscount * hpShare(threat, foe) - hpShare(taken, incoming)
+}
+
+// hpShare is the part of the pokemon's remaining hp the damage takes, plus the knock out bonus
+func hpShare(damage float64, pokemon *api.Pokemon) float64 {
+	if pokemon.CurrHp <= 0 {
+		return 0
+	}
+	if damage >= float64(pokemon.CurrHp) {
+		return 1 + knockOutBonus
+	}
+	return damage / float64(pokemon.CurrHp)
+}
+
+// movesFirst follows the battle's turn order: priority bracket first, then effective speed. A speed
+// tie is counted as moving second
+func movesFirst(b *battle.Battle, self *api.Pokemon, move *api.MoveInstance, foe *api.Pokemon, foeMove *api.MoveInstance) bool {
+	if foeMove == nil {
+		return true
+	}
+	if move.Detail.Priority != foeMove.Detail.Priority {
+		return move.Detail.Priority > foeMove.Detail.Priority
+	}
+	return damageCalculator.CalcEffectiveStat(self, b.Context, "speed") > damageCalculator.CalcEffectiveStat(foe, b.Context, "speed")
+}
@@ -98,12 +98,52 @@ type UnmarshaledPokemonInfo struct {
 	EntryDescr		  string
 	BaseHappiness     int
 	CaptureRate       int
+	GrowthRate        string
 }
 // Pokemon Species Structs
 type UnmarshaledPokemonSpecies struct {
 	FlavorText        []FlavorText `json:"flavor_text_entries"`
 	BaseHappiness     int `json:"base_happiness"`
 	CaptureRate       int `json:"capture_rate"`
+	GrowthRate        NamedResource `json:"growth_rate"`
+	EvolutionChain    NamedResource `json:"evolution_chain"` // only the url is set
+}
+type NamedResource struct {
+	Name              string `json:"name"`
+	Url               string `json:"url"`
+}
+// Evolution Chain Structs
+type UnmarshaledEvolutionChain struct {
+	Id                int `json:"id"`
+	Chain             ChainLink `json:"chain"`
+}
+// ChainLink is one species in the chain, with the conditions for evolving into it from the link above
+type ChainLink struct {
+	Species           NamedResource `json:"species"`
+	EvolutionDetails  []EvolutionDetail `json:"evolution_details"`
+	EvolvesTo         []ChainLink `json:"evolves_to"`
+}
+// EvolutionDetail is one way of evolving, every condition that is set has to be met. Pointers are nil
+// when the api sends null
+type EvolutionDetail struct {
+	Trigger               NamedResource `json:"trigger"`
+	MinLevel              int `json:"min_level"`
+	MinHappiness          int `json:"min_happiness"`
+	MinAffection          int `json:"min_affection"`
+	MinBeauty             int `json:"min_beauty"`
+	Item                  *NamedResource `json:"item"`
+	HeldItem              *NamedResource `json:"held_item"`
+	KnownMove             *NamedResource `json:"known_move"`
+	KnownMoveType         *NamedResource `json:"known_move_type"`
+	Location              *NamedResource `json:"location"`
+	PartySpecies          *NamedResource `json:"party_species"`
+	PartyType             *NamedResource `json:"party_type"`
+	TradeSpecies          *NamedResource `json:"trade_species"`
+	Gender                *int `json:"gender"`
+	RelativePhysicalStats *int `json:"relative_physical_stats"` // 1 attack > defense, 0 equal, -1 attack < defense
+	TimeOfDay             string `json:"time_of_day"`
+	NeedsOverworldRain    bool `json:"needs_overworld_rain"`
+	TurnUpsideDown        bool `json:"turn_upside_down"`
 }
 type FlavorText struct {
 	EntryDescr        string `json:"flavor_text"`
@@ -185,6 +225,25 @@ type Relations struct {
 type BattleContext struct {
 	Rng                *rand.Rand
 	PokemonStates      map[*Pokemon]PokemonBattleState
+	TypeChart          *TypeEffect
+	Weather            FieldState // sun, rain, sandstorm, hail or snow
+	Terrain            FieldState // electric, grassy, psychic or misty
+	Sides              [2]SideConditions // indexed by PokemonBattleState.Side
+}
+// SideConditions are the screens and entry hazards on one side of the field
+type SideConditions struct {
+	Reflect            int // turns left
+	LightScreen        int
+	AuroraVeil         int
+	Spikes             int // layers, up to 3
+	ToxicSpikes        int // layers, up to 2
+	StealthRock        bool
+	StickyWeb          bool
+}
+// FieldState is a weather or terrain in effect, an empty Name means there is none
+type FieldState struct {
+	Name               string
+	TurnsLeft          int
 }
 /*
 type PokemonBattleState struct {
@@ -219,6 +278,10 @@ type PokemonBattleState struct {
 	ActiveMoveKind     string
 	UsedMinimize       bool
 	CanFlee            bool
+	Protected          bool // protected by protect / detect for the rest of this turn
+	ProtectCount       int // consecutive successful protections, each one makes the next less likely
+	Flinched           bool // hit by a flinching move before moving this turn
+	Side               int // which side of the field the pokemon is on, 0 for the user and 1 for the opponent
 }
 type SemiInvulnState struct {
 	Move               *MoveDetail
@@ -255,6 +318,8 @@ type RampageState struct {
 	WillConfuse        bool
 }
 type Pokemon struct {
+	Id               int // unique within the user's box, 0 for wild and npc pokemon
+	Nickname         string
 	Species          string
 	Level            int
 	CurrHp           int
@@ -265,13 +330,18 @@ type Pokemon struct {
 	Stats            map[string]BundleStats
 	AccuracyStage    int
 	EvasionStage     int
-	Weight           float32
+	Weight           float32 // in hectograms like the api reports it
+	Friendship       int // 0-255, starts at the species' base happiness
+	Experience       int // total experience points, see the experience package for the level curves
+	BaseExp          int // the species' base experience yield when defeated
+	GrowthRate       string // the species' growth rate curve, empty on pokemon saved before it was recorded
 }
 type BundleStats struct {
 	StatValue        int
 	EVValue          int
 	IVValue          int
-	EffortValue      int
+	EffortValue      int // the evs this pokemon yields when it is defeated
+	BaseStat         int // the species' base stat, 0 on pokemon saved before it was recorded
 }
 type MoveList struct {
 	LevelUpMoves     map[int][]string
@@ -0,0 +1,486 @@
+package battle
+
+import (
+	"math/rand"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/box"
+	"github.com/rashadat1/goPokedex/internal/damageCalculator"
+	"github.com/rashadat1/goPokedex/internal/statCalculator"
+)
+
+type Side int
+
+const (
+	SideUser Side = iota
+	SideOpponent
+)
+
+func (s Side) Opponent() Side {
+	return 1 - s
+}
+
+type ActionKind int
+
+const (
+	ActionFight ActionKind = iota
+	ActionRun
+	ActionRecharge // forced on a pokemon that used hyper-beam and the like last turn
+	ActionSwitch
+	ActionPass // the pokemon has no move it can use and does nothing
+)
+
+// Action is what one side chose to do this turn
+type Action struct {
+	Kind           ActionKind
+	MoveIndex      int // slot in Pokemon.Moves (0 based) for ActionFight
+	Slot           int // party slot (0 based) to send in for ActionSwitch
+}
+
+func Fight(moveIndex int) Action {
+	return Action{Kind: ActionFight, MoveIndex: moveIndex}
+}
+func Run() Action {
+	return Action{Kind: ActionRun}
+}
+func Switch(slot int) Action {
+	return Action{Kind: ActionSwitch, Slot: slot}
+}
+func Pass() Action {
+	return Action{Kind: ActionPass}
+}
+
+// Battle resolves turns between two parties. It does no I/O - every turn returns the events
+// that happened in order and the caller decides how to show them
+type Battle struct {
+	Parties        [2]*Party
+	Pokemon        [2]*api.Pokemon // the active pokemon of each party
+	Context        *api.BattleContext
+	Turn           int
+	Over           bool
+	Fled           bool // the battle ended because a side ran away
+	Winner         Side // only meaningful when Over and not Fled
+	// ChooseReplacement picks who comes in after a faint or a u-turn style move, nil sends in the first usable pokemon
+	ChooseReplacement [2]Chooser
+}
+
+// New starts a battle between two single pokemon
+func New(user, opponent *api.Pokemon, typeChart *api.TypeEffect, rng *rand.Rand) *Battle {
+	return NewTeamBattle(&Party{Members: []*api.Pokemon{user}}, &Party{Members: []*api.Pokemon{opponent}}, typeChart, rng)
+}
+
+// NewTeamBattle starts a battle between two parties led by their active pokemon. The battle only
+// ends once every pokemon on one side has fainted (or a side runs)
+func NewTeamBattle(user, opponent *Party, typeChart *api.TypeEffect, rng *rand.Rand) *Battle {
+	battleContext := &api.BattleContext{
+		Rng: rng,
+		PokemonStates: make(map[*api.Pokemon]api.PokemonBattleState),
+		TypeChart: typeChart,
+	}
+	for side, party := range []*Party{user, opponent} {
+		party.Fought = make([]bool, len(party.Members))
+		party.Fought[party.Active] = true
+		for _, pokemon := range party.Members {
+			pokemon.AccuracyStage = 0
+			pokemon.EvasionStage = 0
+			battleContext.PokemonStates[pokemon] = freshState(Side(side), nil)
+		}
+	}
+	return &Battle{
+		Parties: [2]*Party{user, opponent},
+		Pokemon: [2]*api.Pokemon{user.ActivePokemon(), opponent.ActivePokemon()},
+		Context: battleContext,
+	}
+}
+
+// PlayTurn resolves both sides' actions (indexed by Side) and returns what happened
+func (b *Battle) PlayTurn(actions [2]Action) []Event {
+	if b.Over {
+		return nil
+	}
+	b.Turn++
+	events := []Event{{Kind: EventTurnStart, Amount: b.Turn}}
+
+	// a pokemon in the middle of a multi-turn move carries on whatever was chosen
+	for _, side := range []Side{SideUser, SideOpponent} {
+		if forced, ok := b.ForcedAction(side); ok {
+			actions[side] = forced
+		}
+	}
+	var stuck [2]bool
+	for _, side := range []Side{SideUser, SideOpponent} {
+		if actions[side].Kind != ActionRun {
+			continue
+		}
+		if !b.Context.PokemonStates[b.Pokemon[side]].CanFlee {
+			events = append(events, Event{Kind: EventCantEscape, Side: side, Pokemon: box.DisplayName(b.Pokemon[side])})
+			stuck[side] = true
+			continue
+		}
+		events = append(events, Event{Kind: EventRun, Side: side, Pokemon: box.DisplayName(b.Pokemon[side])})
+		b.Over = true
+		b.Fled = true
+		return events
+	}
+
+	// the order is settled once per turn so a speed tie is only ever flipped once. A side that switches
+	// doesn't move afterwards, so the order worked out before switching still holds for the movers
+	order := b.turnOrder(actions)
+
+	// switches happen before any move, faster pokemon first
+	for _, side := range order {
+		if actions[side].Kind != ActionSwitch {
+			continue
+		}
+		pokemon := b.Pokemon[side]
+		switch {
+		case !b.Parties[side].CanSwitchTo(actions[side].Slot):
+			events = append(events, Event{Kind: EventFail, Side: side, Pokemon: box.DisplayName(pokemon)})
+		case !b.Context.PokemonStates[pokemon].CanFlee:
+			events = append(events, Event{Kind: EventCantEscape, Side: side, Pokemon: box.DisplayName(pokemon)})
+		default:
+			events = append(events, b.switchIn(side, actions[side].Slot, "")...)
+		}
+		stuck[side] = true
+	}
+
+	// a pokemon that was switched out by a move before its turn came doesn't get to act
+	active := b.Pokemon
+	movers := []Side{}
+	for _, side := range order {
+		if !stuck[side] {
+			movers = append(movers, side)
+		}
+	}
+	for i, side := range movers {
+		if b.Over {
+			break
+		}
+		if b.Pokemon[side] != active[side] {
+			continue
+		}
+		movesLast := i == len(movers) - 1
+		if actions[side].Kind == ActionRecharge {
+			events = append(events, b.recharge(side)...)
+			continue
+		}
+		if actions[side].Kind == ActionPass {
+			events = append(events, Event{Kind: EventPass, Side: side, Pokemon: box.DisplayName(b.Pokemon[side])})
+			continue
+		}
+		events = append(events, b.useMove(side, actions[side].MoveIndex, movesLast)...)
+	}
+	events = append(events, b.endTurn()...)
+	return append(events, b.replaceFainted()...)
+}
+
+// ForcedAction is the action a pokemon has to take this turn without being asked - recharging, or
+// carrying on with a charging, semi-invulnerable, rampage or lock-in move
+func (b *Battle) ForcedAction(side Side) (Action, bool) {
+	pokemon := b.Pokemon[side]
+	state := b.Context.PokemonStates[pokemon]
+	if state.Recharging {
+		return Action{Kind: ActionRecharge}, true
+	}
+	if state.ActiveMove == "" {
+		return Action{}, false
+	}
+	for i, moveInst := range pokemon.Moves {
+		if moveInst != nil && moveInst.Detail.Name == state.ActiveMove {
+			return Fight(i), true
+		}
+	}
+	return Action{}, false
+}
+
+func (b *Battle) recharge(side Side) []Event {
+	pokemon := b.Pokemon[side]
+	state := b.Context.PokemonStates[pokemon]
+	state.Recharging = false
+	b.Context.PokemonStates[pokemon] = state
+	return []Event{{Kind: EventRecharge, Side: side, Pokemon: box.DisplayName(pokemon)}}
+}
+
+// turnOrder resolves who moves first: the higher priority bracket always goes first, within a bracket
+// the higher effective speed (stages and paralysis included) wins and exact ties are a seeded coin flip
+func (b *Battle) turnOrder(actions [2]Action) []Side {
+	userPriority := b.movePriority(SideUser, actions[SideUser])
+	oppPriority := b.movePriority(SideOpponent, actions[SideOpponent])
+	if userPriority != oppPriority {
+		if userPriority > oppPriority {
+			return []Side{SideUser, SideOpponent}
+		}
+		return []Side{SideOpponent, SideUser}
+	}
+	userSpeed := damageCalculator.CalcEffectiveStat(b.Pokemon[SideUser], b.Context, "speed")
+	oppSpeed := damageCalculator.CalcEffectiveStat(b.Pokemon[SideOpponent], b.Context, "speed")
+	if userSpeed > oppSpeed || (userSpeed == oppSpeed && b.Context.Rng.Intn(2) == 0) {
+		return []Side{SideUser, SideOpponent}
+	}
+	return []Side{SideOpponent, SideUser}
+}
+func (b *Battle) movePriority(side Side, action Action) int {
+	if action.Kind != ActionFight {
+		return 0
+	}
+	moveInst := MoveAt(b.Pokemon[side], action.MoveIndex)
+	if moveInst == nil {
+		return 0
+	}
+	return moveInst.Detail.Priority
+}
+
+// MoveAt is the move in the slot, nil when the slot is empty or out of range
+func MoveAt(pokemon *api.Pokemon, moveIndex int) *api.MoveInstance {
+	if moveIndex < 0 || moveIndex >= len(pokemon.Moves) {
+		return nil
+	}
+	moveInst := pokemon.Moves[moveIndex]
+	if moveInst == nil || moveInst.Detail == nil {
+		return nil
+	}
+	return moveInst
+}
+
+func (b *Battle) useMove(side Side, moveIndex int, movesLast bool) []Event {
+	attacker := b.Pokemon[side]
+	defender := b.Pokemon[side.Opponent()]
+	if attacker.CurrHp <= 0 {
+		return nil
+	}
+	moveInst := MoveAt(attacker, moveIndex)
+	if moveInst == nil {
+		// nothing in that slot, a hand-edited replay can ask for one
+		return []Event{{Kind: EventFail, Side: side, Pokemon: box.DisplayName(attacker)}}
+	}
+	canMove, events := b.canMove(side)
+	if !canMove {
+		damageCalculator.ClearMoveState(attacker, b.Context)
+		return events
+	}
+	move := moveInst.Detail
+	events = append(events, Event{Kind: EventMove, Side: side, Pokemon: box.DisplayName(attacker), Move: move.Name})
+	if defender.CurrHp <= 0 && damageCalculator.TargetsOpponent(move) {
+		// the target fainted earlier in the turn and its replacement isn't in yet
+		return append(events, Event{Kind: EventFail, Side: side, Pokemon: box.DisplayName(attacker)})
+	}
+
+	attackerState := b.Context.PokemonStates[attacker]
+	if damageCalculator.ProtectionMoves[move.Name] {
+		moveInst.RemainingPP--
+		return append(events, b.protect(side, movesLast)...)
+	}
+	attackerState.ProtectCount = 0
+	b.Context.PokemonStates[attacker] = attackerState
+
+	defenderState := b.Context.PokemonStates[defender]
+	blockable := damageCalculator.TargetsOpponent(move) && !damageCalculator.MovesThatDamageThroughProtection[move.Name] && !damageCalculator.IsChargeTurn(attacker, move, b.Context)
+	if defenderState.Protected && blockable {
+		if !damageCalculator.MovesThatBreakProtection[move.Name] {
+			if !damageCalculator.IsContinuation(attacker, move, b.Context) {
+				moveInst.RemainingPP--
+			}
+			damageCalculator.ClearMoveState(attacker, b.Context)
+			return append(events, Event{Kind: EventProtected, Side: side.Opponent(), Pokemon: box.DisplayName(defender)})
+		}
+		defenderState.Protected = false
+		b.Context.PokemonStates[defender] = defenderState
+		events = append(events, Event{Kind: EventProtectionBroken, Side: side.Opponent(), Pokemon: box.DisplayName(defender)})
+	}
+
+	if damageCalculator.TerrainBlocksPriority(defender, move, b.Context.Terrain.Name) {
+		moveInst.RemainingPP--
+		return append(events, Event{Kind: EventProtected, Side: side.Opponent(), Pokemon: box.DisplayName(defender), Detail: "psychic"})
+	}
+
+	moveOutcome := damageCalculator.HandleMoveExecution(attacker, defender, moveInst, b.Context)
+	if moveOutcome.Message != "" {
+		events = append(events, Event{Kind: EventMessage, Side: side, Pokemon: box.DisplayName(attacker), Detail: moveOutcome.Message})
+	}
+	if moveOutcome.Charging {
+		return events
+	}
+	if moveOutcome.Missed {
+		events = append(events, Event{Kind: EventMiss, Side: side, Pokemon: box.DisplayName(attacker), Move: moveInst.Detail.Name})
+		events = append(events, b.userHpChange(side, -moveOutcome.CrashDamage, "crash")...)
+		return append(events, b.checkFaint(side)...)
+	}
+	if moveOutcome.Effectiveness == 0 {
+		events = append(events, Event{Kind: EventNoEffect, Side: side.Opponent(), Pokemon: box.DisplayName(defender)})
+		events = append(events, b.userHpChange(side, -moveOutcome.CrashDamage, "crash")...)
+		return append(events, b.checkFaint(side)...)
+	}
+	dealt := 0
+	if moveOutcome.Damage > 0 {
+		dealt = b.applyDamage(defender, moveOutcome.Damage)
+		events = append(events, Event{
+			Kind: EventDamage,
+			Side: side.Opponent(),
+			Pokemon: box.DisplayName(defender),
+			Move: moveInst.Detail.Name,
+			Amount: dealt,
+			Hits: moveOutcome.NumHits,
+			Critical: moveOutcome.Critical,
+			Effectiveness: moveOutcome.Effectiveness,
+		})
+	}
+	recoil := damageCalculator.RecoilFromDamage(dealt, moveOutcome.RecoilDamageMultiplier)
+	if recoil > 0 {
+		events = append(events, b.userHpChange(side, -recoil, "recoil")...)
+	} else {
+		events = append(events, b.userHpChange(side, -recoil, "drain")...)
+	}
+	events = append(events, b.userHpChange(side, moveOutcome.Healing, "heal")...)
+	if moveOutcome.FieldChange != "" {
+		events = append(events, Event{Kind: EventFieldStart, Side: side, Pokemon: box.DisplayName(attacker), Move: move.Name, Detail: moveOutcome.FieldChange})
+	}
+	events = append(events, b.sideConditionEvents(moveOutcome)...)
+	events = append(events, b.statusEvents(side, move, moveOutcome)...)
+	events = append(events, b.statChangeEvents(side, move, moveOutcome)...)
+	if moveOutcome.Flinched && moveOutcome.Damage > 0 && !movesLast {
+		b.flinch(side.Opponent())
+	}
+	events = append(events, b.checkFaint(side.Opponent())...)
+	events = append(events, b.checkFaint(side)...)
+	if moveOutcome.StatusFailed {
+		return events
+	}
+	if damageCalculator.MovesThatSwitchTheTargetOut[move.Name] {
+		events = append(events, b.dragOut(side, move)...)
+	}
+	if damageCalculator.MovesThatSwitchTheUserOut[move.Name] {
+		events = append(events, b.switchUserOut(side, move)...)
+	}
+	return events
+}
+
+// userHpChange applies healing (positive) or self inflicted damage (negative) to the side's pokemon
+// with hp kept between 0 and max, reporting what actually changed. Detail says where it came from
+func (b *Battle) userHpChange(side Side, change int, detail string) []Event {
+	pokemon := b.Pokemon[side]
+	if change == 0 || pokemon.CurrHp <= 0 {
+		return nil
+	}
+	if change < 0 {
+		lost := b.applyDamage(pokemon, -change)
+		return []Event{{Kind: EventRecoil, Side: side, Pokemon: box.DisplayName(pokemon), Amount: lost, Detail: detail}}
+	}
+	healed := min(change, pokemon.Stats["hp"].StatValue - pokemon.CurrHp)
+	if healed <= 0 {
+		return nil
+	}
+	pokemon.CurrHp += healed
+	return []Event{{Kind: EventHeal, Side: side, Pokemon: box.DisplayName(pokemon), Amount: healed, Detail: detail}}
+}
+
+// protect succeeds with probability (1/3)^n where n is the number of protections in a row,
+// and always fails when there is nobody left to move after the user
+func (b *Battle) protect(side Side, movesLast bool) []Event {
+	pokemon := b.Pokemon[side]
+	state := b.Context.PokemonStates[pokemon]
+	chance := 1.0
+	for i := 0; i < state.ProtectCount; i++ {
+		chance /= 3
+	}
+	if movesLast || b.Context.Rng.Float64() >= chance {
+		state.ProtectCount = 0
+		b.Context.PokemonStates[pokemon] = state
+		return []Event{{Kind: EventFail, Side: side, Pokemon: box.DisplayName(pokemon)}}
+	}
+	state.Protected = true
+	state.ProtectCount++
+	b.Context.PokemonStates[pokemon] = state
+	return []Event{{Kind: EventProtect, Side: side, Pokemon: box.DisplayName(pokemon)}}
+}
+
+// endTurn deals residual status damage and clears everything that only lasts for the turn it was set in
+func (b *Battle) endTurn() []Event {
+	events := []Event{}
+	for _, side := range []Side{SideUser, SideOpponent} {
+		if !b.Over {
+			events = append(events, b.residualDamage(side)...)
+		}
+		if !b.Over {
+			events = append(events, b.trapDamage(side)...)
+		}
+	}
+	events = append(events, b.fieldEffects()...)
+	events = append(events, b.tickScreens()...)
+	for _, pokemon := range b.Pokemon {
+		state := b.Context.PokemonStates[pokemon]
+		state.Protected = false
+		state.Flinched = false
+		b.Context.PokemonStates[pokemon] = state
+	}
+	return events
+}
+
+// statChangeEvents reports every stage the move changed in the move's own order, including the
+// ones that couldn't move because they were already at +6 or -6
+func (b *Battle) statChangeEvents(side Side, move *api.MoveDetail, moveOutcome *damageCalculator.MoveOutcome) []Event {
+	targetSide, changes := side.Opponent(), moveOutcome.TargetStatChanges
+	if damageCalculator.StatChangesTargetUser(move) {
+		targetSide, changes = side, moveOutcome.UserStatChanges
+	}
+	name := box.DisplayName(b.Pokemon[targetSide])
+	events := []Event{}
+	for _, statChange := range move.StatChange {
+		applied, ok := changes[statChange.Stat.Name]
+		if !ok {
+			continue
+		}
+		if applied == 0 {
+			events = append(events, Event{Kind: EventStatLimit, Side: targetSide, Pokemon: name, Amount: statChange.Change, Detail: statChange.Stat.Name})
+			continue
+		}
+		events = append(events, Event{Kind: EventStatChange, Side: targetSide, Pokemon: name, Amount: applied, Detail: statChange.Stat.Name})
+	}
+	return events
+}
+
+// applyDamage takes hp off the pokemon without going below 0 and returns how much was actually lost
+func (b *Battle) applyDamage(pokemon *api.Pokemon, damage int) int {
+	dealt := min(damage, pokemon.CurrHp)
+	pokemon.CurrHp -= dealt
+	return dealt
+}
+
+func (b *Battle) checkFaint(side Side) []Event {
+	pokemon := b.Pokemon[side]
+	if pokemon.CurrHp > 0 {
+		return nil
+	}
+	damageCalculator.ClearMoveState(pokemon, b.Context)
+	events := []Event{{Kind: EventFaint, Side: side, Pokemon: box.DisplayName(pokemon)}}
+	events = append(events, b.awardEVs(side.Opponent(), pokemon)...)
+	if b.Over || !b.Parties[side].Defeated() {
+		// either the other side already lost earlier this turn and keeps the win, or there
+		// is someone left to send in at the end of the turn
+		return events
+	}
+	b.Over = true
+	b.Winner = side.Opponent()
+	return append(events, Event{Kind: EventWin, Side: b.Winner, Pokemon: box.DisplayName(b.Pokemon[b.Winner])})
+}
+
+// awardEVs gives the side's pokemon the ev yield of the pokemon it just defeated and brings its
+// stats up to date
+func (b *Battle) awardEVs(side Side, defeated *api.Pokemon) []Event {
+	winner := b.Pokemon[side]
+	if winner.CurrHp <= 0 {
+		return nil
+	}
+	gained := statCalculator.AwardEVs(winner, defeated)
+	if len(gained) == 0 {
+		return nil
+	}
+	// pokemon without known base stats keep their evs and get their stats worked out later
+	statCalculator.RecalculateStats(winner)
+	events := []Event{}
+	for _, stat := range statCalculator.StatNames {
+		if gain, ok := gained[stat]; ok {
+			events = append(events, Event{Kind: EventEffort, Side: side, Pokemon: box.DisplayName(winner), Amount: gain, Detail: stat})
+		}
+	}
+	return events
+}
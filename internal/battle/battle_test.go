@@ -0,0 +1,697 @@
+package battle
+
+import (
+	"math/rand"
+	"testing"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/fixtures"
+)
+
+func testTypeChart() *api.TypeEffect {
+	return &api.TypeEffect{
+		TypeMap: map[string]api.Relations{
+			"normal": {Effectiveness: map[string]float32{"ghost": 0}},
+			"fire": {Effectiveness: map[string]float32{"grass": 2}},
+			"fighting": {Effectiveness: map[string]float32{"ghost": 0}},
+		},
+	}
+}
+func eventKinds(events []Event) []EventKind {
+	kinds := []EventKind{}
+	for _, event := range events {
+		kinds = append(kinds, event.Kind)
+	}
+	return kinds
+}
+
+func TestFasterPokemonMovesFirst(t *testing.T) {
+	tackle := fixtures.Move("tackle", "normal", "physical", 40, 0)
+	user := fixtures.Pokemon("rattata", []string{"normal"}, 200, 90, tackle)
+	opponent := fixtures.Pokemon("geodude", []string{"rock"}, 200, 20, tackle)
+	currentBattle := New(user, opponent, testTypeChart(), rand.New(rand.NewSource(1)))
+
+	events := currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})
+	moves := []Side{}
+	for _, event := range events {
+		if event.Kind == EventMove {
+			moves = append(moves, event.Side)
+		}
+	}
+	if len(moves) != 2 || moves[0] != SideUser || moves[1] != SideOpponent {
+		t.Errorf("Got move order %v expected the faster user to move first", moves)
+	}
+	if user.Moves[0].RemainingPP != 34 || opponent.Moves[0].RemainingPP != 34 {
+		t.Errorf("expected each side to spend one PP")
+	}
+	if user.CurrHp >= 200 || opponent.CurrHp >= 200 {
+		t.Errorf("expected both pokemon to take damage")
+	}
+}
+
+func TestSpeedTiesAreSeeded(t *testing.T) {
+	firstMovers := func(seed int64) []Side {
+		order := []Side{}
+		for i := 0; i < 20; i++ {
+			tackle := fixtures.Move("tackle", "normal", "physical", 40, 0)
+			user := fixtures.Pokemon("rattata", []string{"normal"}, 500, 50, tackle)
+			opponent := fixtures.Pokemon("rattata", []string{"normal"}, 500, 50, tackle)
+			currentBattle := New(user, opponent, testTypeChart(), rand.New(rand.NewSource(seed + int64(i))))
+			for _, event := range currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)}) {
+				if event.Kind == EventMove {
+					order = append(order, event.Side)
+					break
+				}
+			}
+		}
+		return order
+	}
+	first, second := firstMovers(7), firstMovers(7)
+	sawUser, sawOpponent := false, false
+	for i := range first {
+		if first[i] != second[i] {
+			t.Fatalf("speed tie resolved differently with the same seed")
+		}
+		sawUser = sawUser || first[i] == SideUser
+		sawOpponent = sawOpponent || first[i] == SideOpponent
+	}
+	if !sawUser || !sawOpponent {
+		t.Errorf("expected speed ties to be won by both sides over 20 seeds")
+	}
+}
+
+// countingSource counts how many numbers the battle draws
+type countingSource struct {
+	rand.Source
+	draws       int
+}
+
+func (s *countingSource) Int63() int64 {
+	s.draws++
+	return s.Source.Int63()
+}
+
+func TestSpeedTieIsFlippedOncePerTurn(t *testing.T) {
+	tackle := fixtures.Move("tackle", "normal", "physical", 40, 0)
+	user := fixtures.Pokemon("rattata", []string{"normal"}, 500, 50, tackle)
+	opponent := fixtures.Pokemon("rattata", []string{"normal"}, 500, 50, tackle)
+	source := &countingSource{Source: rand.NewSource(7)}
+	currentBattle := New(user, opponent, testTypeChart(), rand.New(source))
+	// passing draws nothing, so the only draw is the tie break
+	currentBattle.PlayTurn([2]Action{Pass(), Pass()})
+	if source.draws != 1 {
+		t.Errorf("Got %d draws expected the tie to be broken once", source.draws)
+	}
+}
+
+func TestFaintEndsBattle(t *testing.T) {
+	ember := fixtures.Move("ember", "fire", "special", 40, 0)
+	tackle := fixtures.Move("tackle", "normal", "physical", 40, 0)
+	user := fixtures.Pokemon("charmander", []string{"fire"}, 200, 90, ember)
+	opponent := fixtures.Pokemon("bulbasaur", []string{"grass"}, 1, 20, tackle)
+	currentBattle := New(user, opponent, testTypeChart(), rand.New(rand.NewSource(1)))
+
+	events := currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})
+	expected := []EventKind{EventTurnStart, EventMove, EventDamage, EventFaint, EventWin}
+	kinds := eventKinds(events)
+	if len(kinds) != len(expected) {
+		t.Fatalf("Got events %v expected %v", kinds, expected)
+	}
+	for i := range expected {
+		if kinds[i] != expected[i] {
+			t.Errorf("Got events %v expected %v", kinds, expected)
+			break
+		}
+	}
+	if events[2].Amount != 1 || events[2].Effectiveness != 2 {
+		t.Errorf("Got %+v expected 1 super effective damage (hp is clamped at 0)", events[2])
+	}
+	if !currentBattle.Over || currentBattle.Winner != SideUser || opponent.CurrHp != 0 {
+		t.Errorf("expected the user to win once the opponent fainted")
+	}
+	if currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)}) != nil {
+		t.Errorf("expected no more turns once the battle is over")
+	}
+}
+
+func TestMissingMoveSlotFails(t *testing.T) {
+	tackle := fixtures.Move("tackle", "normal", "physical", 40, 0)
+	user := fixtures.Pokemon("rattata", []string{"normal"}, 100, 90, tackle)
+	opponent := fixtures.Pokemon("pidgey", []string{"normal"}, 100, 20, tackle)
+	currentBattle := New(user, opponent, testTypeChart(), rand.New(rand.NewSource(1)))
+
+	// an empty slot and one past the end both fail instead of panicking
+	events := currentBattle.PlayTurn([2]Action{Fight(1), Fight(7)})
+	kinds := eventKinds(events)
+	if len(kinds) != 3 || kinds[1] != EventFail || kinds[2] != EventFail {
+		t.Errorf("Got events %v expected both sides' moves to fail", kinds)
+	}
+	if user.CurrHp != 100 || opponent.CurrHp != 100 {
+		t.Errorf("Got %d and %d hp expected neither pokemon to be hurt", user.CurrHp, opponent.CurrHp)
+	}
+}
+
+func TestImmunityAndRun(t *testing.T) {
+	tackle := fixtures.Move("tackle", "normal", "physical", 40, 0)
+	user := fixtures.Pokemon("rattata", []string{"normal"}, 100, 90, tackle)
+	opponent := fixtures.Pokemon("gastly", []string{"ghost"}, 100, 20, tackle)
+	currentBattle := New(user, opponent, testTypeChart(), rand.New(rand.NewSource(1)))
+
+	events := currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})
+	if events[2].Kind != EventNoEffect || opponent.CurrHp != 100 {
+		t.Errorf("Got %v expected the ghost to be unaffected by tackle", eventKinds(events))
+	}
+	events = currentBattle.PlayTurn([2]Action{Run(), Fight(0)})
+	if events[len(events) - 1].Kind != EventRun || !currentBattle.Over || !currentBattle.Fled {
+		t.Errorf("Got %v expected the user to run away", eventKinds(events))
+	}
+}
+
+func firstMover(events []Event) Side {
+	for _, event := range events {
+		if event.Kind == EventMove {
+			return event.Side
+		}
+	}
+	return -1
+}
+
+func TestPriorityAndEffectiveSpeed(t *testing.T) {
+	tackle := fixtures.Move("tackle", "normal", "physical", 40, 0)
+	quickAttack := fixtures.Move("quick-attack", "normal", "physical", 40, 0)
+	quickAttack.Priority = 1
+
+	user := fixtures.Pokemon("rattata", []string{"normal"}, 500, 20, tackle, quickAttack)
+	opponent := fixtures.Pokemon("jolteon", []string{"electric"}, 500, 130, tackle)
+	currentBattle := New(user, opponent, testTypeChart(), rand.New(rand.NewSource(1)))
+	if side := firstMover(currentBattle.PlayTurn([2]Action{Fight(1), Fight(0)})); side != SideUser {
+		t.Errorf("expected quick-attack to move before a faster pokemon's tackle")
+	}
+	if side := firstMover(currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})); side != SideOpponent {
+		t.Errorf("expected the faster pokemon to move first within the same bracket")
+	}
+
+	// +6 speed on the user and paralysis on the opponent flip the order
+	userState := currentBattle.Context.PokemonStates[user]
+	userState.StatStages["speed"] = 6
+	currentBattle.Context.PokemonStates[user] = userState
+	oppState := currentBattle.Context.PokemonStates[opponent]
+	oppState.Ailment = &api.AilmentState{Name: "paralysis"}
+	currentBattle.Context.PokemonStates[opponent] = oppState
+	if side := firstMover(currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})); side != SideUser {
+		t.Errorf("expected stat stages and paralysis to be part of the speed comparison")
+	}
+}
+
+func TestProtect(t *testing.T) {
+	tackle := fixtures.Move("tackle", "normal", "physical", 40, 0)
+	protect := fixtures.Move("protect", "normal", "status", 0, 0)
+	protect.Priority = 4
+	protect.Target = api.TargetType{Name: "user"}
+
+	user := fixtures.Pokemon("rattata", []string{"normal"}, 500, 20, protect)
+	opponent := fixtures.Pokemon("pidgey", []string{"normal"}, 500, 90, tackle, protect)
+	currentBattle := New(user, opponent, testTypeChart(), rand.New(rand.NewSource(1)))
+
+	events := currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})
+	kinds := eventKinds(events)
+	if kinds[2] != EventProtect || kinds[4] != EventProtected || user.CurrHp != 500 {
+		t.Errorf("Got %v expected protect to block tackle", kinds)
+	}
+	if currentBattle.Context.PokemonStates[user].Protected {
+		t.Errorf("expected protection to wear off at the end of the turn")
+	}
+
+	// both protect: the slower pokemon moves last so its protect fails
+	events = currentBattle.PlayTurn([2]Action{Fight(0), Fight(1)})
+	kinds = eventKinds(events)
+	if kinds[len(kinds) - 1] != EventFail {
+		t.Errorf("Got %v expected the protect used last in the turn to fail", kinds)
+	}
+}
+
+func residualDamage(events []Event) []int {
+	amounts := []int{}
+	for _, event := range events {
+		if event.Kind == EventResidual {
+			amounts = append(amounts, event.Amount)
+		}
+	}
+	return amounts
+}
+
+func TestBadPoisonEscalatesAndImmunities(t *testing.T) {
+	toxic := fixtures.Move("toxic", "poison", "status", 0, 0)
+	toxic.Meta.Ailment.Name = "poison"
+	toxic.Meta.Category.Name = "ailment"
+	growl := fixtures.Move("growl", "normal", "status", 0, 0)
+
+	user := fixtures.Pokemon("grimer", []string{"poison"}, 500, 90, toxic, growl)
+	opponent := fixtures.Pokemon("rattata", []string{"normal"}, 160, 20, growl)
+	currentBattle := New(user, opponent, testTypeChart(), rand.New(rand.NewSource(1)))
+
+	events := currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})
+	if kinds := eventKinds(events); kinds[2] != EventStatus || events[2].Detail != "bad-poison" {
+		t.Fatalf("Got %v expected toxic to badly poison the target", kinds)
+	}
+	expected := []int{10, 20, 30}
+	got := residualDamage(events)
+	for i := 0; i < 2; i++ {
+		got = append(got, residualDamage(currentBattle.PlayTurn([2]Action{Fight(1), Fight(0)}))...)
+	}
+	for i := range expected {
+		if i >= len(got) || got[i] != expected[i] {
+			t.Errorf("Got residual damage %v expected %v", got, expected)
+			break
+		}
+	}
+	events = currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})
+	if kinds := eventKinds(events); kinds[2] != EventFail {
+		t.Errorf("Got %v expected toxic to fail on an already poisoned target", kinds)
+	}
+
+	steelUser := fixtures.Pokemon("grimer", []string{"poison"}, 500, 90, toxic)
+	steelOpponent := fixtures.Pokemon("magnemite", []string{"electric", "steel"}, 160, 20, growl)
+	currentBattle = New(steelUser, steelOpponent, testTypeChart(), rand.New(rand.NewSource(1)))
+	events = currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})
+	if kinds := eventKinds(events); kinds[2] != EventFail || currentBattle.Context.PokemonStates[steelOpponent].Ailment != nil {
+		t.Errorf("Got %v expected steel types to be immune to poison", kinds)
+	}
+}
+
+func TestSleepStopsMovesUntilItWearsOff(t *testing.T) {
+	tackle := fixtures.Move("tackle", "normal", "physical", 40, 0)
+	user := fixtures.Pokemon("rattata", []string{"normal"}, 500, 90, tackle)
+	opponent := fixtures.Pokemon("snorlax", []string{"normal"}, 500, 20, tackle)
+	currentBattle := New(user, opponent, testTypeChart(), rand.New(rand.NewSource(1)))
+	userState := currentBattle.Context.PokemonStates[user]
+	userState.Ailment = &api.AilmentState{Name: "sleep", MaxTurns: 2}
+	currentBattle.Context.PokemonStates[user] = userState
+
+	for turn := 1; turn <= 3; turn++ {
+		events := currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})
+		asleep := events[1].Kind == EventCantMove && events[1].Detail == "sleep"
+		if turn <= 2 && !asleep {
+			t.Errorf("turn %d: Got %v expected the user to stay asleep", turn, eventKinds(events))
+		}
+		if turn == 3 && (events[1].Kind != EventStatusCured || events[2].Kind != EventMove) {
+			t.Errorf("turn %d: Got %v expected the user to wake up and attack", turn, eventKinds(events))
+		}
+	}
+}
+
+func TestTrappingChipsAndBlocksRun(t *testing.T) {
+	wrap := fixtures.Move("wrap", "normal", "physical", 15, 0)
+	wrap.Meta.Ailment.Name = "trap"
+	growl := fixtures.Move("growl", "normal", "status", 0, 0)
+	user := fixtures.Pokemon("rattata", []string{"normal"}, 400, 20, growl)
+	opponent := fixtures.Pokemon("ekans", []string{"poison"}, 400, 90, wrap, growl)
+	currentBattle := New(user, opponent, testTypeChart(), rand.New(rand.NewSource(1)))
+
+	events := currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})
+	if amounts := residualDamage(events); len(amounts) != 1 || amounts[0] != 50 {
+		t.Errorf("Got residual damage %v expected 1/8 of max hp", amounts)
+	}
+	events = currentBattle.PlayTurn([2]Action{Run(), Fight(1)})
+	if kinds := eventKinds(events); kinds[1] != EventCantEscape || currentBattle.Over {
+		t.Errorf("Got %v expected the trapped user to be unable to run", kinds)
+	}
+	for i := 0; i < 5 && !currentBattle.Context.PokemonStates[user].CanFlee; i++ {
+		currentBattle.PlayTurn([2]Action{Fight(0), Fight(1)})
+	}
+	if state := currentBattle.Context.PokemonStates[user]; state.Trapped != nil || !state.CanFlee {
+		t.Errorf("expected the binding to wear off after 4-5 turns")
+	}
+}
+
+func TestFlinchOnlyStopsTheSlowerPokemon(t *testing.T) {
+	fakeOut := fixtures.Move("fake-out", "normal", "physical", 40, 0)
+	fakeOut.Meta.FlinchChance = 100
+	tackle := fixtures.Move("tackle", "normal", "physical", 40, 0)
+
+	user := fixtures.Pokemon("rattata", []string{"normal"}, 500, 90, fakeOut, tackle)
+	opponent := fixtures.Pokemon("snorlax", []string{"normal"}, 500, 20, fakeOut, tackle)
+	currentBattle := New(user, opponent, testTypeChart(), rand.New(rand.NewSource(1)))
+	events := currentBattle.PlayTurn([2]Action{Fight(0), Fight(1)})
+	if last := events[len(events) - 1]; last.Kind != EventCantMove || last.Detail != "flinch" {
+		t.Errorf("Got %v expected the slower pokemon to flinch", eventKinds(events))
+	}
+	hp := user.CurrHp
+	events = currentBattle.PlayTurn([2]Action{Fight(1), Fight(0)})
+	if user.CurrHp == hp || events[len(events) - 1].Kind != EventDamage {
+		t.Errorf("Got %v expected a flinch from the slower pokemon to do nothing", eventKinds(events))
+	}
+}
+
+func TestRampageEndsInConfusion(t *testing.T) {
+	thrash := fixtures.Move("thrash", "normal", "physical", 120, 0)
+	growl := fixtures.Move("growl", "normal", "status", 0, 0)
+	user := fixtures.Pokemon("tauros", []string{"normal"}, 2000, 90, thrash, growl)
+	opponent := fixtures.Pokemon("snorlax", []string{"normal"}, 2000, 20, growl)
+	currentBattle := New(user, opponent, testTypeChart(), rand.New(rand.NewSource(1)))
+
+	thrashes := 0
+	for turn := 0; turn < 3 && currentBattle.Context.PokemonStates[user].Confused == nil; turn++ {
+		// choosing growl is ignored while the user is locked into thrash
+		for _, event := range currentBattle.PlayTurn([2]Action{Fight(thrashes % 2), Fight(0)}) {
+			if event.Kind == EventMove && event.Side == SideUser && event.Move == "thrash" {
+				thrashes++
+			}
+		}
+	}
+	if thrashes < 2 || thrashes > 3 {
+		t.Errorf("Got %d thrashes expected the rampage to last 2-3 turns", thrashes)
+	}
+	state := currentBattle.Context.PokemonStates[user]
+	if state.Confused == nil || state.Rampaging != nil {
+		t.Errorf("expected the rampage to end with the user confused")
+	}
+}
+
+func TestSemiInvulnerableAndRecharge(t *testing.T) {
+	fly := fixtures.Move("fly", "flying", "physical", 90, 0)
+	hyperBeam := fixtures.Move("hyper-beam", "normal", "special", 150, 0)
+	tackle := fixtures.Move("tackle", "normal", "physical", 40, 0)
+	gust := fixtures.Move("gust", "flying", "special", 40, 0)
+
+	user := fixtures.Pokemon("pidgeot", []string{"flying"}, 1000, 90, fly, hyperBeam)
+	opponent := fixtures.Pokemon("snorlax", []string{"normal"}, 1000, 20, tackle, gust)
+	currentBattle := New(user, opponent, testTypeChart(), rand.New(rand.NewSource(1)))
+
+	events := currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})
+	if kinds := eventKinds(events); kinds[2] != EventMessage || kinds[len(kinds) - 1] != EventMiss || user.CurrHp != 1000 {
+		t.Errorf("Got %v expected fly to charge and tackle to miss the airborne user", kinds)
+	}
+	forced, ok := currentBattle.ForcedAction(SideUser)
+	if !ok || forced != Fight(0) {
+		t.Fatalf("expected the user to be forced to finish fly")
+	}
+	// whatever the user picks it finishes fly, and gust can hit it before it lands
+	events = currentBattle.PlayTurn([2]Action{Fight(1), Fight(1)})
+	if firstMover(events) != SideUser || events[1].Move != "fly" || opponent.CurrHp == 1000 {
+		t.Errorf("Got %v expected fly to land on the second turn", eventKinds(events))
+	}
+	if user.Moves[0].RemainingPP != 34 {
+		t.Errorf("Got %d pp expected fly to use one pp over both turns", user.Moves[0].RemainingPP)
+	}
+
+	currentBattle.PlayTurn([2]Action{Fight(1), Fight(0)})
+	forced, ok = currentBattle.ForcedAction(SideUser)
+	if !ok || forced.Kind != ActionRecharge {
+		t.Fatalf("expected hyper-beam to need a recharge")
+	}
+	events = currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})
+	if kinds := eventKinds(events); kinds[1] != EventRecharge {
+		t.Errorf("Got %v expected the user to spend the turn recharging", kinds)
+	}
+	if _, ok := currentBattle.ForcedAction(SideUser); ok {
+		t.Errorf("expected the user to be free to choose after recharging")
+	}
+}
+
+func TestRolloutDoublesEachTurn(t *testing.T) {
+	rollout := fixtures.Move("rollout", "rock", "physical", 30, 0)
+	growl := fixtures.Move("growl", "normal", "status", 0, 0)
+	user := fixtures.Pokemon("geodude", []string{"rock"}, 1000, 90, rollout)
+	opponent := fixtures.Pokemon("snorlax", []string{"normal"}, 100000, 20, growl)
+	opponent.Stats["hp"] = api.BundleStats{StatValue: 100000}
+	currentBattle := New(user, opponent, testTypeChart(), rand.New(rand.NewSource(1)))
+
+	// damage roughly doubles each turn and the lock-in ends after the fifth hit
+	damages := []int{}
+	for turn := 0; turn < 6; turn++ {
+		hp := opponent.CurrHp
+		currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})
+		damages = append(damages, hp - opponent.CurrHp)
+		lockedIn := currentBattle.Context.PokemonStates[user].LockedIn
+		if (turn == 4) != (lockedIn == nil) {
+			t.Errorf("turn %d: expected the user to stay locked into rollout for exactly 5 turns", turn + 1)
+		}
+	}
+	for i := 1; i < 5; i++ {
+		if damages[i] < damages[i - 1] * 3 / 2 {
+			t.Errorf("Got damage %v expected rollout to double in power each hit", damages)
+			break
+		}
+	}
+	if damages[5] > damages[1] {
+		t.Errorf("Got damage %v expected rollout to start again from base power", damages)
+	}
+}
+
+func statMove(name, target, category string, changes map[string]int) *api.MoveDetail {
+	move := fixtures.Move(name, "normal", "status", 0, 0)
+	move.Target = api.TargetType{Name: target}
+	move.Meta.Category.Name = category
+	for stat, change := range changes {
+		move.StatChange = append(move.StatChange, api.StatChange{Change: change, Stat: api.Stat{Name: stat}})
+	}
+	return move
+}
+
+func TestStatStagesClampAndTarget(t *testing.T) {
+	swordsDance := statMove("swords-dance", "user", "net-good-stats", map[string]int{"attack": 2})
+	growl := statMove("growl", "all-opponents", "net-good-stats", map[string]int{"attack": -1})
+	closeCombat := fixtures.Move("close-combat", "fighting", "physical", 120, 0)
+	closeCombat.Meta.Category.Name = "damage+raise"
+	closeCombat.StatChange = []api.StatChange{{Change: -1, Stat: api.Stat{Name: "defense"}}, {Change: -1, Stat: api.Stat{Name: "special-defense"}}}
+
+	splash := statMove("splash", "user", "unique", nil)
+
+	user := fixtures.Pokemon("scizor", []string{"bug"}, 5000, 90, swordsDance, closeCombat)
+	opponent := fixtures.Pokemon("snorlax", []string{"normal"}, 5000, 20, growl, splash)
+	currentBattle := New(user, opponent, testTypeChart(), rand.New(rand.NewSource(1)))
+
+	var events []Event
+	for i := 0; i < 6; i++ {
+		events = currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})
+	}
+	// each turn nets +1 until +2 from +5 clamps at +6 and growl takes it back to +5
+	if stage := currentBattle.Context.PokemonStates[user].StatStages["attack"]; stage != 5 {
+		t.Errorf("Got attack stage %d expected 5", stage)
+	}
+	if kinds := eventKinds(events); kinds[2] != EventStatChange || events[2].Amount != 1 || events[4].Amount != -1 {
+		t.Errorf("Got %v expected swords dance to climb only one stage from +5", kinds)
+	}
+	currentBattle.PlayTurn([2]Action{Fight(0), Fight(1)})
+	events = currentBattle.PlayTurn([2]Action{Fight(0), Fight(1)})
+	if events[2].Kind != EventStatLimit || events[2].Amount != 2 {
+		t.Errorf("Got %v expected the attack stage to be at its limit", eventKinds(events))
+	}
+
+	events = currentBattle.PlayTurn([2]Action{Fight(1), Fight(0)})
+	state := currentBattle.Context.PokemonStates[user]
+	if state.StatStages["defense"] != -1 || state.StatStages["special-defense"] != -1 {
+		t.Errorf("Got %v expected close combat to lower the user's defenses", state.StatStages)
+	}
+}
+
+func TestDrainRecoilHealingAndCrash(t *testing.T) {
+	gigaDrain := fixtures.Move("giga-drain", "grass", "special", 75, 0)
+	gigaDrain.Meta.Drain = 50
+	doubleEdge := fixtures.Move("double-edge", "normal", "physical", 120, 0)
+	doubleEdge.Meta.Drain = -33
+	recover := fixtures.Move("recover", "normal", "status", 0, 0)
+	recover.Target = api.TargetType{Name: "user"}
+	recover.Meta.Healing = 50
+	highJumpKick := fixtures.Move("high-jump-kick", "fighting", "physical", 130, 0)
+	growl := fixtures.Move("growl", "normal", "status", 0, 0)
+
+	user := fixtures.Pokemon("breloom", []string{"grass"}, 300, 90, gigaDrain, doubleEdge, recover, highJumpKick)
+	opponent := fixtures.Pokemon("gastly", []string{"ghost"}, 5000, 20, growl)
+	currentBattle := New(user, opponent, testTypeChart(), rand.New(rand.NewSource(1)))
+
+	events := currentBattle.PlayTurn([2]Action{Fight(2), Fight(0)})
+	if events[2].Kind != EventFail {
+		t.Errorf("Got %v expected recover to fail at full hp", eventKinds(events))
+	}
+	user.CurrHp = 100
+	events = currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})
+	if events[3].Kind != EventHeal || events[3].Amount != events[2].Amount / 2 || user.CurrHp != 100 + events[3].Amount {
+		t.Errorf("Got %+v expected giga drain to heal half the damage dealt", events)
+	}
+	user.CurrHp = 250
+	events = currentBattle.PlayTurn([2]Action{Fight(2), Fight(0)})
+	if events[2].Kind != EventHeal || events[2].Amount != 50 || user.CurrHp != 300 {
+		t.Errorf("Got %+v expected recover to heal up to max hp", events)
+	}
+
+	// the ghost is immune so double-edge deals nothing and costs nothing, but a crash still hurts
+	events = currentBattle.PlayTurn([2]Action{Fight(1), Fight(0)})
+	if user.CurrHp != 300 {
+		t.Errorf("Got %v expected no recoil without damage", eventKinds(events))
+	}
+	events = currentBattle.PlayTurn([2]Action{Fight(3), Fight(0)})
+	if events[3].Kind != EventRecoil || events[3].Detail != "crash" || user.CurrHp != 150 {
+		t.Errorf("Got %+v expected high jump kick to crash for half max hp", events)
+	}
+
+	normalOpponent := fixtures.Pokemon("snorlax", []string{"normal"}, 5000, 20, growl)
+	currentBattle = New(user, normalOpponent, testTypeChart(), rand.New(rand.NewSource(1)))
+	events = currentBattle.PlayTurn([2]Action{Fight(1), Fight(0)})
+	if events[3].Kind != EventRecoil || events[3].Amount != int(float32(events[2].Amount) * 0.33) {
+		t.Errorf("Got %+v expected double edge to recoil a third of the damage", events)
+	}
+}
+
+func TestWeatherAndTerrain(t *testing.T) {
+	sunnyDay := fixtures.Move("sunny-day", "fire", "status", 0, 0)
+	sunnyDay.Target = api.TargetType{Name: "entire-field"}
+	solarBeam := fixtures.Move("solar-beam", "grass", "special", 120, 0)
+	sandstorm := fixtures.Move("sandstorm", "rock", "status", 0, 0)
+	sandstorm.Target = api.TargetType{Name: "entire-field"}
+	growl := fixtures.Move("growl", "normal", "status", 0, 0)
+
+	user := fixtures.Pokemon("venusaur", []string{"grass"}, 1600, 90, sunnyDay, solarBeam, sandstorm)
+	opponent := fixtures.Pokemon("geodude", []string{"rock"}, 100000, 20, growl)
+	currentBattle := New(user, opponent, testTypeChart(), rand.New(rand.NewSource(1)))
+
+	events := currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})
+	if events[2].Kind != EventFieldStart || currentBattle.Context.Weather.Name != "sun" || currentBattle.Context.Weather.TurnsLeft != 4 {
+		t.Fatalf("Got %v expected sunny day to start 5 turns of sun", eventKinds(events))
+	}
+	events = currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})
+	if events[2].Kind != EventFail {
+		t.Errorf("Got %v expected sunny day to fail while it is already sunny", eventKinds(events))
+	}
+	events = currentBattle.PlayTurn([2]Action{Fight(1), Fight(0)})
+	if events[2].Kind != EventDamage {
+		t.Errorf("Got %v expected solar beam to fire without charging in the sun", eventKinds(events))
+	}
+
+	events = currentBattle.PlayTurn([2]Action{Fight(2), Fight(0)})
+	if currentBattle.Context.Weather.Name != "sandstorm" {
+		t.Fatalf("expected sandstorm to replace the sun")
+	}
+	// the rock type is immune, the grass type takes 1/16
+	if amounts := residualDamage(events); len(amounts) != 1 || amounts[0] != 100 {
+		t.Errorf("Got chip damage %v expected only the user to take 1/16", amounts)
+	}
+	for i := 0; i < 4; i++ {
+		events = currentBattle.PlayTurn([2]Action{Fight(1), Fight(0)})
+	}
+	if last := events[len(events) - 1]; last.Kind != EventFieldEnd || last.Detail != "sandstorm" || currentBattle.Context.Weather.Name != "" {
+		t.Errorf("Got %v expected the sandstorm to end after 5 turns", eventKinds(events))
+	}
+
+	// electric terrain keeps grounded pokemon awake
+	spore := fixtures.Move("spore", "grass", "status", 0, 0)
+	spore.Meta.Ailment.Name = "sleep"
+	spore.Meta.Category.Name = "ailment"
+	currentBattle = New(fixtures.Pokemon("breloom", []string{"grass"}, 500, 90, spore), fixtures.Pokemon("pikachu", []string{"electric"}, 500, 20, growl), testTypeChart(), rand.New(rand.NewSource(1)))
+	currentBattle.Context.Terrain = api.FieldState{Name: "electric", TurnsLeft: 5}
+	events = currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})
+	if events[2].Kind != EventFail {
+		t.Errorf("Got %v expected spore to fail on electric terrain", eventKinds(events))
+	}
+}
+
+func TestScreensAndHazards(t *testing.T) {
+	reflect := fixtures.Move("reflect", "psychic", "status", 0, 0)
+	reflect.Target = api.TargetType{Name: "users-field"}
+	stealthRock := fixtures.Move("stealth-rock", "rock", "status", 0, 0)
+	stealthRock.Target = api.TargetType{Name: "opponents-field"}
+	spikes := fixtures.Move("spikes", "ground", "status", 0, 0)
+	spikes.Target = api.TargetType{Name: "opponents-field"}
+	defog := fixtures.Move("defog", "flying", "status", 0, 0)
+	brickBreak := fixtures.Move("brick-break", "fighting", "physical", 75, 0)
+	growl := fixtures.Move("growl", "normal", "status", 0, 0)
+
+	user := fixtures.Pokemon("skarmory", []string{"steel", "flying"}, 1000, 90, reflect, stealthRock, spikes, defog)
+	opponent := fixtures.Pokemon("machamp", []string{"fighting"}, 800, 20, growl, brickBreak)
+	currentBattle := New(user, opponent, testTypeChart(), rand.New(rand.NewSource(1)))
+
+	events := currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})
+	if events[2].Kind != EventSideStart || events[2].Side != SideUser || currentBattle.Context.Sides[SideUser].Reflect != 4 {
+		t.Fatalf("Got %v expected reflect to go up on the user's side for 5 turns", eventKinds(events))
+	}
+	events = currentBattle.PlayTurn([2]Action{Fight(1), Fight(1)})
+	if sides := currentBattle.Context.Sides[SideUser]; sides.Reflect != 0 || events[len(events) - 1].Kind != EventSideEnd {
+		t.Errorf("Got %v expected brick break to shatter reflect", eventKinds(events))
+	}
+	currentBattle.PlayTurn([2]Action{Fight(2), Fight(0)})
+	currentBattle.PlayTurn([2]Action{Fight(2), Fight(0)})
+	if sides := currentBattle.Context.Sides[SideOpponent]; !sides.StealthRock || sides.Spikes != 2 {
+		t.Fatalf("Got %+v expected stealth rock and two layers of spikes on the foe's side", sides)
+	}
+
+	// 1/8 from stealth rock (neutral) and 1/6 from two layers of spikes on the way in
+	opponent.CurrHp = 800
+	events = currentBattle.entryHazards(SideOpponent)
+	if amounts := residualDamage(events); len(amounts) != 2 || amounts[0] != 100 || amounts[1] != 133 {
+		t.Errorf("Got hazard damage %v expected [100 133]", amounts)
+	}
+
+	events = currentBattle.PlayTurn([2]Action{Fight(3), Fight(0)})
+	if sides := currentBattle.Context.Sides[SideOpponent]; sides.StealthRock || sides.Spikes != 0 {
+		t.Errorf("Got %v expected defog to clear the hazards", eventKinds(events))
+	}
+}
+
+func TestTeamBattleSwitching(t *testing.T) {
+	tackle := fixtures.Move("tackle", "normal", "physical", 40, 0)
+	rattata := fixtures.Pokemon("rattata", []string{"normal"}, 1, 90, tackle)
+	pidgey := fixtures.Pokemon("pidgey", []string{"normal", "flying"}, 500, 10, tackle)
+	geodude := fixtures.Pokemon("geodude", []string{"rock"}, 500, 50, tackle)
+	userParty, err := NewParty(rattata, pidgey)
+	if err != nil {
+		t.Fatal(err)
+	}
+	oppParty, _ := NewParty(geodude)
+	currentBattle := NewTeamBattle(userParty, oppParty, testTypeChart(), rand.New(rand.NewSource(1)))
+
+	// the switch happens before the foe's attack, which lands on the pokemon that came in
+	events := currentBattle.PlayTurn([2]Action{Switch(1), Fight(0)})
+	if currentBattle.Pokemon[SideUser] != pidgey || events[1].Kind != EventSwitchOut || events[2].Kind != EventSwitchIn {
+		t.Fatalf("Got %v expected rattata to switch out for pidgey", eventKinds(events))
+	}
+	if events[4].Kind != EventDamage || events[4].Pokemon != "pidgey" {
+		t.Errorf("Got %v expected the tackle to hit pidgey", eventKinds(events))
+	}
+	if fought := currentBattle.Parties[SideUser].Fought; !fought[0] || !fought[1] {
+		t.Errorf("Got fought %v expected both pokemon to have been on the field", fought)
+	}
+
+	// a fainted pokemon is replaced at the end of the turn and the battle carries on
+	events = currentBattle.PlayTurn([2]Action{Switch(0), Fight(0)})
+	if currentBattle.Over || currentBattle.Pokemon[SideUser] != pidgey || events[len(events) - 1].Kind != EventSwitchIn {
+		t.Fatalf("Got %v expected pidgey to replace the fainted rattata", eventKinds(events))
+	}
+	if currentBattle.Parties[SideUser].CanSwitchTo(0) {
+		t.Errorf("Got a fainted rattata as a switch option expected it to be unusable")
+	}
+	if standing := currentBattle.Parties[SideUser].Standing(); standing != 1 {
+		t.Errorf("Got %d pokemon standing expected only pidgey", standing)
+	}
+
+	// the battle ends once the last pokemon on a side faints
+	pidgey.CurrHp = 1
+	currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})
+	if !currentBattle.Over || currentBattle.Winner != SideOpponent {
+		t.Errorf("Got over %v winner %v expected the foe to win", currentBattle.Over, currentBattle.Winner)
+	}
+	// the fainted pokemon left on the field doesn't count as standing
+	if standing := currentBattle.Parties[SideUser].Standing(); standing != 0 {
+		t.Errorf("Got %d pokemon standing expected none", standing)
+	}
+
+	// u-turn takes the user out after the hit and roar drags the replacement back out
+	uTurn := fixtures.Move("u-turn", "bug", "physical", 70, 0)
+	roar := fixtures.Move("roar", "normal", "status", 0, 0)
+	roar.Priority = -6
+	scyther := fixtures.Pokemon("scyther", []string{"bug", "flying"}, 500, 90, uTurn)
+	pidgey = fixtures.Pokemon("pidgey", []string{"normal", "flying"}, 500, 10, tackle)
+	userParty, _ = NewParty(scyther, pidgey)
+	oppParty, _ = NewParty(fixtures.Pokemon("arcanine", []string{"fire"}, 500, 50, roar))
+	currentBattle = NewTeamBattle(userParty, oppParty, testTypeChart(), rand.New(rand.NewSource(1)))
+	events = currentBattle.PlayTurn([2]Action{Fight(0), Fight(0)})
+	switchIns := []string{}
+	for _, event := range events {
+		if event.Kind == EventSwitchIn {
+			switchIns = append(switchIns, event.Pokemon + " " + event.Move)
+		}
+	}
+	if len(switchIns) != 2 || switchIns[0] != "pidgey u-turn" || switchIns[1] != "scyther roar" || currentBattle.Pokemon[SideUser] != scyther {
+		t.Errorf("Got switch ins %v expected pidgey from u-turn then scyther from roar", switchIns)
+	}
+}
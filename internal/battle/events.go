@@ -0,0 +1,53 @@
+package battle
+
+type EventKind string
+
+const (
+	EventTurnStart          EventKind = "turn-start" // Amount is the turn number
+	EventMove               EventKind = "move"
+	EventMessage            EventKind = "message" // flavour text from the move in Detail
+	EventMiss               EventKind = "miss"
+	EventNoEffect           EventKind = "no-effect"
+	EventDamage             EventKind = "damage"
+	EventFaint              EventKind = "faint"
+	EventRun                EventKind = "run"
+	EventWin                EventKind = "win"
+	EventFail               EventKind = "fail" // the move failed
+	EventProtect            EventKind = "protect" // the pokemon protected itself
+	EventProtected          EventKind = "protected" // an attack was blocked by the pokemon's protection (or the terrain in Detail)
+	EventProtectionBroken   EventKind = "protection-broken"
+	EventStatus             EventKind = "status" // the pokemon was afflicted with the condition in Detail
+	EventStatusCured        EventKind = "status-cured" // the pokemon recovered from the condition in Detail
+	EventCantMove           EventKind = "cant-move" // sleep, freeze, flinch or full paralysis (Detail) stopped the pokemon
+	EventResidual           EventKind = "residual" // end of turn (or switch-in hazard) damage from the condition in Detail
+	EventConfused           EventKind = "confused" // the pokemon is confused and may hurt itself
+	EventSelfHit            EventKind = "self-hit" // Amount is the confusion damage the pokemon dealt itself
+	EventCantEscape         EventKind = "cant-escape" // the pokemon is trapped and could not run
+	EventRecharge           EventKind = "recharge" // the pokemon spent the turn recharging
+	EventPass               EventKind = "pass" // the pokemon had no move it could use
+	EventStatChange         EventKind = "stat-change" // Amount is how many stages the stat in Detail moved
+	EventHeal               EventKind = "heal" // Amount hp restored by a healing ("heal") or drain ("drain") move or "grassy" terrain
+	EventRecoil             EventKind = "recoil" // Amount hp the pokemon cost itself through "recoil", "crash" or "heal" (an hp cost)
+	EventFieldStart         EventKind = "field-start" // the weather or terrain in Detail started
+	EventFieldEnd           EventKind = "field-end" // the weather or terrain in Detail ended
+	EventSideStart          EventKind = "side-start" // the screen or hazard in Detail went up on Side
+	EventSideEnd            EventKind = "side-end" // the screen or hazard in Detail is gone from Side
+	EventStatLimit          EventKind = "stat-limit" // the stat in Detail was already at the limit in the direction of Amount
+	EventSwitchOut          EventKind = "switch-out" // the pokemon was withdrawn, Move is what forced it out if anything
+	EventSwitchIn           EventKind = "switch-in" // the pokemon was sent out, Move is what brought it in if anything
+	EventEffort             EventKind = "effort" // the pokemon gained Amount evs in the stat in Detail for a knock out
+)
+
+// Event is one thing that happened during a turn. Side and Pokemon always describe the
+// pokemon the event happened to - for damage that is the defender
+type Event struct {
+	Kind           EventKind
+	Side           Side
+	Pokemon        string
+	Move           string
+	Amount         int
+	Hits           int
+	Critical       bool
+	Effectiveness  float64
+	Detail         string
+}
@@ -0,0 +1,47 @@
+package battle
+
+import (
+	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/box"
+	"github.com/rashadat1/goPokedex/internal/damageCalculator"
+)
+
+// fieldEffects runs the end of turn weather and terrain effects: sandstorm and hail chip damage,
+// grassy terrain healing grounded pokemon, and then both counters ticking down
+func (b *Battle) fieldEffects() []Event {
+	events := []Event{}
+	weather := b.Context.Weather.Name
+	for _, side := range []Side{SideUser, SideOpponent} {
+		pokemon := b.Pokemon[side]
+		if b.Over || pokemon.CurrHp <= 0 {
+			continue
+		}
+		if chip := damageCalculator.WeatherChipDamage(pokemon, weather); chip > 0 {
+			dealt := b.applyDamage(pokemon, chip)
+			events = append(events, Event{Kind: EventResidual, Side: side, Pokemon: box.DisplayName(pokemon), Amount: dealt, Detail: weather})
+			events = append(events, b.checkFaint(side)...)
+		}
+	}
+	if b.Context.Terrain.Name == "grassy" {
+		for _, side := range []Side{SideUser, SideOpponent} {
+			if !b.Over && damageCalculator.IsGrounded(b.Pokemon[side]) {
+				events = append(events, b.userHpChange(side, max(b.Pokemon[side].Stats["hp"].StatValue / 16, 1), "grassy")...)
+			}
+		}
+	}
+	events = append(events, tickField(&b.Context.Weather)...)
+	return append(events, tickField(&b.Context.Terrain)...)
+}
+
+func tickField(field *api.FieldState) []Event {
+	if field.Name == "" {
+		return nil
+	}
+	field.TurnsLeft--
+	if field.TurnsLeft > 0 {
+		return nil
+	}
+	ended := field.Name
+	*field = api.FieldState{}
+	return []Event{{Kind: EventFieldEnd, Detail: ended}}
+}
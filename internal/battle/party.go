@@ -0,0 +1,213 @@
+package battle
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/box"
+)
+
+const MaxPartySize = 6
+
+// Party is the team a side brings to the battle. Active is the slot of the pokemon on the field and
+// Fought marks every slot that has been on the field, the pokemon that share the experience
+type Party struct {
+	Members        []*api.Pokemon
+	Active         int
+	Fought         []bool
+}
+
+// NewParty builds a party of one to six pokemon led by the first one that can still fight
+func NewParty(members ...*api.Pokemon) (*Party, error) {
+	if len(members) == 0 {
+		return nil, errors.New("a party needs at least one pokemon")
+	}
+	if len(members) > MaxPartySize {
+		return nil, fmt.Errorf("a party holds at most %d pokemon, got %d", MaxPartySize, len(members))
+	}
+	party := &Party{Members: members, Active: -1}
+	for i, pokemon := range members {
+		if pokemon == nil {
+			return nil, fmt.Errorf("party slot %d is empty", i + 1)
+		}
+		if party.Active == -1 && pokemon.CurrHp > 0 {
+			party.Active = i
+		}
+	}
+	if party.Active == -1 {
+		return nil, errors.New("every pokemon in the party has fainted")
+	}
+	return party, nil
+}
+
+func (p *Party) ActivePokemon() *api.Pokemon {
+	return p.Members[p.Active]
+}
+
+// CanSwitchTo reports whether the pokemon in the slot could come in: it has to exist, be
+// out of battle and still be able to fight
+func (p *Party) CanSwitchTo(slot int) bool {
+	return slot >= 0 && slot < len(p.Members) && slot != p.Active && p.Members[slot].CurrHp > 0
+}
+
+// Usable lists the slots of every pokemon that could be switched in
+func (p *Party) Usable() []int {
+	slots := []int{}
+	for i := range p.Members {
+		if p.CanSwitchTo(i) {
+			slots = append(slots, i)
+		}
+	}
+	return slots
+}
+
+// Standing counts the pokemon that can still fight, the one on the field included
+func (p *Party) Standing() int {
+	standing := 0
+	for _, pokemon := range p.Members {
+		if pokemon.CurrHp > 0 {
+			standing++
+		}
+	}
+	return standing
+}
+
+// Defeated is true once every pokemon in the party has fainted
+func (p *Party) Defeated() bool {
+	for _, pokemon := range p.Members {
+		if pokemon.CurrHp > 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Chooser picks the party slot to send in when a side has to replace its pokemon mid battle
+type Chooser func(b *Battle, side Side) int
+
+// FirstUsable sends in the first pokemon in party order that can still fight
+func FirstUsable(b *Battle, side Side) int {
+	usable := b.Parties[side].Usable()
+	if len(usable) == 0 {
+		return -1
+	}
+	return usable[0]
+}
+
+// chooseReplacement asks the side's chooser for a slot, falling back to the first usable pokemon
+// when it picks one that can't come in
+func (b *Battle) chooseReplacement(side Side) int {
+	if chooser := b.ChooseReplacement[side]; chooser != nil {
+		if slot := chooser(b, side); b.Parties[side].CanSwitchTo(slot) {
+			return slot
+		}
+	}
+	return FirstUsable(b, side)
+}
+
+// switchIn withdraws the side's pokemon and sends in the one in the slot. Leaving the field clears
+// stat stages and every volatile condition, only the non-volatile ailment stays (with the bad-poison
+// counter starting over). Baton-pass hands the stat stages and confusion to the incoming pokemon.
+// Cause is the move that forced the switch, empty for a switch the side chose
+func (b *Battle) switchIn(side Side, slot int, cause string) []Event {
+	party := b.Parties[side]
+	outgoing, incoming := party.ActivePokemon(), party.Members[slot]
+	events := []Event{}
+	if outgoing.CurrHp > 0 {
+		events = append(events, Event{Kind: EventSwitchOut, Side: side, Pokemon: box.DisplayName(outgoing), Move: cause})
+	}
+	outgoingState := b.Context.PokemonStates[outgoing]
+	incomingState := freshState(side, b.Context.PokemonStates[incoming].Ailment)
+	if cause == "baton-pass" {
+		for stat, stage := range outgoingState.StatStages {
+			incomingState.StatStages[stat] = stage
+		}
+		incomingState.Confused = outgoingState.Confused
+		incoming.AccuracyStage = outgoing.AccuracyStage
+		incoming.EvasionStage = outgoing.EvasionStage
+	}
+	b.Context.PokemonStates[outgoing] = freshState(side, outgoingState.Ailment)
+	outgoing.AccuracyStage = 0
+	outgoing.EvasionStage = 0
+	b.Context.PokemonStates[incoming] = incomingState
+
+	// a binding move ends once its user leaves the field
+	opponent := b.Pokemon[side.Opponent()]
+	if opponentState := b.Context.PokemonStates[opponent]; opponentState.Trapped != nil {
+		trapMove := opponentState.Trapped.Move.Name
+		opponentState.Trapped = nil
+		opponentState.CanFlee = true
+		b.Context.PokemonStates[opponent] = opponentState
+		if opponent.CurrHp > 0 {
+			events = append(events, Event{Kind: EventStatusCured, Side: side.Opponent(), Pokemon: box.DisplayName(opponent), Move: trapMove, Detail: "trap"})
+		}
+	}
+
+	party.Active = slot
+	party.Fought[slot] = true
+	b.Pokemon[side] = incoming
+	events = append(events, Event{Kind: EventSwitchIn, Side: side, Pokemon: box.DisplayName(incoming), Move: cause})
+	return append(events, b.entryHazards(side)...)
+}
+
+// freshState is the battle state of a pokemon that just left or entered the field
+func freshState(side Side, ailment *api.AilmentState) api.PokemonBattleState {
+	if ailment != nil && ailment.Name == "bad-poison" {
+		ailment.Turns = 0
+	}
+	return api.PokemonBattleState{
+		Ailment: ailment,
+		StatStages: make(map[string]int),
+		CanFlee: true,
+		Side: int(side),
+	}
+}
+
+// replaceFainted sends in a replacement for every side whose pokemon fainted, as long as the
+// battle is still going. A replacement knocked out by entry hazards is replaced in turn
+func (b *Battle) replaceFainted() []Event {
+	events := []Event{}
+	for _, side := range []Side{SideUser, SideOpponent} {
+		for !b.Over && b.Pokemon[side].CurrHp <= 0 {
+			slot := b.chooseReplacement(side)
+			if slot < 0 {
+				break
+			}
+			events = append(events, b.switchIn(side, slot, "")...)
+		}
+	}
+	return events
+}
+
+// switchUserOut is u-turn, volt-switch, baton-pass and the like taking the user out after the move
+// landed. Status moves fail when there is nobody to switch to
+func (b *Battle) switchUserOut(side Side, move *api.MoveDetail) []Event {
+	if b.Over || b.Pokemon[side].CurrHp <= 0 {
+		return nil
+	}
+	if len(b.Parties[side].Usable()) == 0 {
+		if move.DamageClass.Name == "status" {
+			return []Event{{Kind: EventFail, Side: side, Pokemon: box.DisplayName(b.Pokemon[side])}}
+		}
+		return nil
+	}
+	return b.switchIn(side, b.chooseReplacement(side), move.Name)
+}
+
+// dragOut is roar, whirlwind, dragon-tail and circle-throw forcing the target out for a random
+// pokemon from its party. Status moves fail when the target has nothing to be replaced with
+func (b *Battle) dragOut(side Side, move *api.MoveDetail) []Event {
+	target := side.Opponent()
+	if b.Over || b.Pokemon[target].CurrHp <= 0 {
+		return nil
+	}
+	usable := b.Parties[target].Usable()
+	if len(usable) == 0 {
+		if move.DamageClass.Name == "status" {
+			return []Event{{Kind: EventFail, Side: side, Pokemon: box.DisplayName(b.Pokemon[side])}}
+		}
+		return nil
+	}
+	return b.switchIn(target, usable[b.Context.Rng.Intn(len(usable))], move.Name)
+}
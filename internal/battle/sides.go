@@ -0,0 +1,56 @@
+package battle
+
+import (
+	"github.com/rashadat1/goPokedex/internal/box"
+	"github.com/rashadat1/goPokedex/internal/damageCalculator"
+)
+
+// sideConditionEvents reports the screens and hazards a move set up or cleared
+func (b *Battle) sideConditionEvents(moveOutcome *damageCalculator.MoveOutcome) []Event {
+	events := []Event{}
+	for _, change := range moveOutcome.SideChanges {
+		kind := EventSideStart
+		if change.Removed {
+			kind = EventSideEnd
+		}
+		events = append(events, Event{Kind: kind, Side: Side(change.Side), Detail: change.Condition})
+	}
+	return events
+}
+
+// tickScreens wears every screen down by a turn at the end of the turn
+func (b *Battle) tickScreens() []Event {
+	events := []Event{}
+	for _, side := range []Side{SideUser, SideOpponent} {
+		for _, ended := range damageCalculator.TickScreens(&b.Context.Sides[side]) {
+			events = append(events, Event{Kind: EventSideEnd, Side: side, Detail: ended})
+		}
+	}
+	return events
+}
+
+// entryHazards runs the hazards on the side against the pokemon that just came in
+func (b *Battle) entryHazards(side Side) []Event {
+	pokemon := b.Pokemon[side]
+	name := box.DisplayName(pokemon)
+	outcome := damageCalculator.ApplyEntryHazards(pokemon, b.Context)
+	events := []Event{}
+	if outcome.StealthRockDamage > 0 {
+		dealt := b.applyDamage(pokemon, outcome.StealthRockDamage)
+		events = append(events, Event{Kind: EventResidual, Side: side, Pokemon: name, Amount: dealt, Detail: "stealth-rock"})
+	}
+	if outcome.SpikesDamage > 0 {
+		dealt := b.applyDamage(pokemon, outcome.SpikesDamage)
+		events = append(events, Event{Kind: EventResidual, Side: side, Pokemon: name, Amount: dealt, Detail: "spikes"})
+	}
+	if outcome.AbsorbedToxicSpikes {
+		events = append(events, Event{Kind: EventSideEnd, Side: side, Pokemon: name, Detail: "toxic-spikes"})
+	}
+	if outcome.Ailment != "" {
+		events = append(events, Event{Kind: EventStatus, Side: side, Pokemon: name, Move: "toxic-spikes", Detail: outcome.Ailment})
+	}
+	if outcome.SpeedDrop {
+		events = append(events, Event{Kind: EventStatChange, Side: side, Pokemon: name, Amount: -1, Detail: "speed"})
+	}
+	return append(events, b.checkFaint(side)...)
+}
@@ -0,0 +1,104 @@
+package battle
+
+import (
+	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/box"
+	"github.com/rashadat1/goPokedex/internal/damageCalculator"
+)
+
+const (
+	thawChance          = 20 // percent chance a frozen pokemon thaws before moving
+	fullParalysisChance = 25
+)
+
+// canMove runs the checks that can stop a pokemon before it acts, in game order: sleep and freeze,
+// flinching, confusion and then full paralysis. Sleep counts down and wakes the pokemon on the turn it
+// runs out, freeze has a flat thaw chance every turn
+func (b *Battle) canMove(side Side) (bool, []Event) {
+	pokemon := b.Pokemon[side]
+	state := b.Context.PokemonStates[pokemon]
+	name := box.DisplayName(pokemon)
+	events := []Event{}
+	if state.Ailment != nil {
+		switch state.Ailment.Name {
+		case "sleep":
+			state.Ailment.Turns++
+			if state.Ailment.Turns <= state.Ailment.MaxTurns {
+				return false, append(events, Event{Kind: EventCantMove, Side: side, Pokemon: name, Detail: "sleep"})
+			}
+			events = append(events, b.cureAilment(side))
+		case "freeze":
+			if b.Context.Rng.Intn(100) >= thawChance {
+				return false, append(events, Event{Kind: EventCantMove, Side: side, Pokemon: name, Detail: "freeze"})
+			}
+			events = append(events, b.cureAilment(side))
+		}
+	}
+	if state.Flinched {
+		return false, append(events, Event{Kind: EventCantMove, Side: side, Pokemon: name, Detail: "flinch"})
+	}
+	canMove, confusionEvents := b.confusionCheck(side)
+	events = append(events, confusionEvents...)
+	if !canMove {
+		return false, events
+	}
+	if state.Ailment != nil && state.Ailment.Name == "paralysis" && b.Context.Rng.Intn(100) < fullParalysisChance {
+		return false, append(events, Event{Kind: EventCantMove, Side: side, Pokemon: name, Detail: "paralysis"})
+	}
+	return true, events
+}
+
+func (b *Battle) cureAilment(side Side) Event {
+	pokemon := b.Pokemon[side]
+	state := b.Context.PokemonStates[pokemon]
+	cured := state.Ailment.Name
+	state.Ailment = nil
+	b.Context.PokemonStates[pokemon] = state
+	return Event{Kind: EventStatusCured, Side: side, Pokemon: box.DisplayName(pokemon), Detail: cured}
+}
+
+// residualDamage is the end of turn chip from burn (1/16), poison (1/8) and bad poison
+// (n/16 where n counts up every turn the pokemon stays badly poisoned)
+func (b *Battle) residualDamage(side Side) []Event {
+	pokemon := b.Pokemon[side]
+	state := b.Context.PokemonStates[pokemon]
+	if pokemon.CurrHp <= 0 || state.Ailment == nil {
+		return nil
+	}
+	maxHp := pokemon.Stats["hp"].StatValue
+	var damage int
+	switch state.Ailment.Name {
+	case "burn":
+		damage = maxHp / 16
+	case "poison":
+		damage = maxHp / 8
+	case "bad-poison":
+		state.Ailment.Turns++
+		damage = maxHp * min(state.Ailment.Turns, 15) / 16
+	default:
+		return nil
+	}
+	dealt := b.applyDamage(pokemon, max(damage, 1))
+	events := []Event{{Kind: EventResidual, Side: side, Pokemon: box.DisplayName(pokemon), Amount: dealt, Detail: state.Ailment.Name}}
+	return append(events, b.checkFaint(side)...)
+}
+
+// statusEvents reports what a move did to the defender's status after it hit, and the confusion
+// a rampage leaves its user with once it ends
+func (b *Battle) statusEvents(side Side, move *api.MoveDetail, moveOutcome *damageCalculator.MoveOutcome) []Event {
+	attacker := b.Pokemon[side]
+	defender := b.Pokemon[side.Opponent()]
+	events := []Event{}
+	if moveOutcome.StatusFailed {
+		events = append(events, Event{Kind: EventFail, Side: side, Pokemon: box.DisplayName(attacker), Move: move.Name})
+	} else if moveOutcome.CausedStatus != "" {
+		events = append(events, Event{Kind: EventStatus, Side: side.Opponent(), Pokemon: box.DisplayName(defender), Move: move.Name, Detail: moveOutcome.CausedStatus})
+	} else if state := b.Context.PokemonStates[defender]; state.Ailment != nil && state.Ailment.Name == "freeze" && move.Type.Name == "fire" && move.DamageClass.Name != "status" && defender.CurrHp > 0 {
+		// a damaging fire move thaws a frozen target out
+		events = append(events, b.cureAilment(side.Opponent()))
+	}
+	if moveOutcome.UserConfused {
+		events = append(events, Event{Kind: EventStatus, Side: side, Pokemon: box.DisplayName(attacker), Move: move.Name, Detail: "confusion"})
+	}
+	return events
+}
@@ -0,0 +1,65 @@
+package battle
+
+import (
+	"github.com/rashadat1/goPokedex/internal/box"
+	"github.com/rashadat1/goPokedex/internal/damageCalculator"
+)
+
+const confusionSelfHitChance = 33 // percent
+
+// confusionCheck counts confusion down each time the pokemon tries to move. While it lasts the
+// pokemon hurts itself instead of moving a third of the time
+func (b *Battle) confusionCheck(side Side) (bool, []Event) {
+	pokemon := b.Pokemon[side]
+	state := b.Context.PokemonStates[pokemon]
+	if state.Confused == nil {
+		return true, nil
+	}
+	name := box.DisplayName(pokemon)
+	state.Confused.CurrentTurns++
+	if state.Confused.CurrentTurns > state.Confused.MaxTurns {
+		state.Confused = nil
+		b.Context.PokemonStates[pokemon] = state
+		return true, []Event{{Kind: EventStatusCured, Side: side, Pokemon: name, Detail: "confusion"}}
+	}
+	events := []Event{{Kind: EventConfused, Side: side, Pokemon: name}}
+	if b.Context.Rng.Intn(100) >= confusionSelfHitChance {
+		return true, events
+	}
+	dealt := b.applyDamage(pokemon, damageCalculator.ConfusionDamage(pokemon, b.Context))
+	events = append(events, Event{Kind: EventSelfHit, Side: side, Pokemon: name, Amount: dealt})
+	return false, append(events, b.checkFaint(side)...)
+}
+
+// flinch only matters when the target still has to move this turn
+func (b *Battle) flinch(side Side) {
+	pokemon := b.Pokemon[side]
+	if pokemon.CurrHp <= 0 {
+		return
+	}
+	state := b.Context.PokemonStates[pokemon]
+	state.Flinched = true
+	b.Context.PokemonStates[pokemon] = state
+}
+
+// trapDamage is the end of turn 1/8 max hp from wrap, bind and the like. The binding
+// lasts 4-5 turns and keeps the pokemon from running until it ends
+func (b *Battle) trapDamage(side Side) []Event {
+	pokemon := b.Pokemon[side]
+	state := b.Context.PokemonStates[pokemon]
+	if pokemon.CurrHp <= 0 || state.Trapped == nil {
+		return nil
+	}
+	name := box.DisplayName(pokemon)
+	trapped := state.Trapped
+	if trapped.CurrentTurns >= trapped.MaxTurns {
+		state.Trapped = nil
+		state.CanFlee = true
+		b.Context.PokemonStates[pokemon] = state
+		return []Event{{Kind: EventStatusCured, Side: side, Pokemon: name, Move: trapped.Move.Name, Detail: "trap"}}
+	}
+	trapped.CurrentTurns++
+	dealt := b.applyDamage(pokemon, max(pokemon.Stats["hp"].StatValue / 8, 1))
+	events := []Event{{Kind: EventResidual, Side: side, Pokemon: name, Move: trapped.Move.Name, Amount: dealt, Detail: "trap"}}
+	return append(events, b.checkFaint(side)...)
+}
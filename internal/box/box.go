@@ -0,0 +1,89 @@
+package box
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+)
+
+// Box holds every pokemon the user has caught as an individual instance - two pikachu
+// are two entries with their own id, level, ivs, nature and moves
+type Box struct {
+	NextId         int `json:"next_id"`
+	Pokemon        []*api.Pokemon `json:"pokemon"`
+}
+
+func New() *Box {
+	return &Box{NextId: 1, Pokemon: []*api.Pokemon{}}
+}
+
+// Deposit gives the pokemon the next free id and stores it
+func (b *Box) Deposit(pokemon api.Pokemon) *api.Pokemon {
+	if b.NextId < 1 {
+		b.NextId = 1
+	}
+	pokemon.Id = b.NextId
+	b.NextId++
+	b.Pokemon = append(b.Pokemon, &pokemon)
+	return &pokemon
+}
+func (b *Box) Get(id int) (*api.Pokemon, bool) {
+	for _, pokemon := range b.Pokemon {
+		if pokemon.Id == id {
+			return pokemon, true
+		}
+	}
+	return nil, false
+}
+func (b *Box) BySpecies(species string) []*api.Pokemon {
+	matches := []*api.Pokemon{}
+	for _, pokemon := range b.Pokemon {
+		if pokemon.Species == species {
+			matches = append(matches, pokemon)
+		}
+	}
+	return matches
+}
+
+// Find resolves what the user typed - an id, a nickname or a species name (only if exactly one is owned)
+func (b *Box) Find(ref string) (*api.Pokemon, error) {
+	if id, err := strconv.Atoi(ref); err == nil {
+		pokemon, ok := b.Get(id)
+		if !ok {
+			return nil, fmt.Errorf("there is no pokemon with id %d in the box", id)
+		}
+		return pokemon, nil
+	}
+	for _, pokemon := range b.Pokemon {
+		if pokemon.Nickname != "" && pokemon.Nickname == ref {
+			return pokemon, nil
+		}
+	}
+	matches := b.BySpecies(ref)
+	if len(matches) == 0 {
+		return nil, fmt.Errorf("you do not own a pokemon called %s", ref)
+	}
+	if len(matches) > 1 {
+		return nil, fmt.Errorf("you own %d %s - use the id shown by the box command", len(matches), ref)
+	}
+	return matches[0], nil
+}
+// Release removes the pokemon with the id, false if there is none. Ids are never handed out again
+func (b *Box) Release(id int) bool {
+	for i, pokemon := range b.Pokemon {
+		if pokemon.Id == id {
+			b.Pokemon = append(b.Pokemon[:i], b.Pokemon[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// DisplayName is the nickname when one was given, otherwise the species
+func DisplayName(pokemon *api.Pokemon) string {
+	if pokemon.Nickname != "" {
+		return pokemon.Nickname
+	}
+	return pokemon.Species
+}
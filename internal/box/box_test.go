@@ -0,0 +1,68 @@
+package box
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+)
+
+func TestDepositAssignsIds(t *testing.T) {
+	b := New()
+	first := b.Deposit(api.Pokemon{Species: "pidgey"})
+	second := b.Deposit(api.Pokemon{Species: "pidgey", Id: 42})
+	if first.Id != 1 || second.Id != 2 || b.NextId != 3 {
+		t.Errorf("Got ids %d and %d with next id %d expected 1, 2 and 3", first.Id, second.Id, b.NextId)
+	}
+	// a box decoded without next_id starts counting at 1
+	b = &Box{}
+	if pokemon := b.Deposit(api.Pokemon{Species: "rattata"}); pokemon.Id != 1 {
+		t.Errorf("Got id %d expected 1", pokemon.Id)
+	}
+}
+
+func TestFind(t *testing.T) {
+	b := New()
+	b.Deposit(api.Pokemon{Species: "pidgey"})
+	b.Deposit(api.Pokemon{Species: "pidgey", Nickname: "gale"})
+	b.Deposit(api.Pokemon{Species: "onix"})
+	cases := []struct {
+		ref         string
+		expectedId  int // 0 when an error is expected
+		err         string
+	}{
+		{ref: "2", expectedId: 2},
+		{ref: "gale", expectedId: 2},
+		{ref: "onix", expectedId: 3},
+		{ref: "pidgey", err: "you own 2 pidgey"},
+		{ref: "7", err: "no pokemon with id 7"},
+		{ref: "mew", err: "do not own a pokemon called mew"},
+	}
+	for _, c := range cases {
+		pokemon, err := b.Find(c.ref)
+		if c.err != "" {
+			if err == nil || !strings.Contains(err.Error(), c.err) {
+				t.Errorf("%s: Got %v expected an error mentioning %q", c.ref, err, c.err)
+			}
+			continue
+		}
+		if err != nil || pokemon.Id != c.expectedId {
+			t.Errorf("%s: Got %+v (%v) expected id %d", c.ref, pokemon, err, c.expectedId)
+		}
+	}
+}
+
+func TestRelease(t *testing.T) {
+	b := New()
+	b.Deposit(api.Pokemon{Species: "pidgey"})
+	b.Deposit(api.Pokemon{Species: "onix"})
+	if !b.Release(1) || b.Release(1) {
+		t.Errorf("expected the first release of id 1 to succeed and the second to fail")
+	}
+	if _, ok := b.Get(1); ok || len(b.Pokemon) != 1 {
+		t.Errorf("Got %d pokemon expected only onix to be left", len(b.Pokemon))
+	}
+	if pokemon := b.Deposit(api.Pokemon{Species: "mew"}); pokemon.Id != 3 {
+		t.Errorf("Got id %d expected released ids not to be reused", pokemon.Id)
+	}
+}
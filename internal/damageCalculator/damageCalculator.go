@@ -2,11 +2,12 @@ package damageCalculator
 
 import (
 	"fmt"
+	"math"
 	"math/rand"
 	"slices"
-	"strings"
 
 	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/box"
 )
 
 type MoveOutcome struct {
@@ -14,13 +15,23 @@ type MoveOutcome struct {
 	StatusDuration            int // only for freeze/sleep
 	NumHits                   int // double-slap / bullet-seed
 	NumTurns                  int // rollout / uproar
-	RecoilDamageMultiplier    float32
+	RecoilDamageMultiplier    float32 // share of the damage dealt the user takes back, negative drains hp instead
+	Healing                   int // hp the user restores as a share of its max hp, negative is an hp cost
+	CrashDamage               int // hp the user loses because its crash move missed
 	CausedStatus              string // paralysis, burn, sleep, etc this can be null
+	StatusFailed              bool // a status move could not afflict the target (already statused or immune)
 	TargetStatChanges         map[string]int
 	UserStatChanges           map[string]int
 	Missed                    bool
 	Flinched                  bool
+	Critical                  bool // at least one hit was a critical hit
+	Effectiveness             float64
+	Breakdown                 DamageBreakdown // breakdown of the last hit
 	Message                   string 
+	Charging                  bool // the move spent this turn charging up or going semi-invulnerable
+	UserConfused              bool // the user's rampage ended and left it confused
+	FieldChange               string // the weather or terrain the move started
+	SideChanges               []SideConditionChange // screens and hazards the move set up or cleared
 }
 // special handling for these classes of moves
 var RampageMoves = map[string]bool{
@@ -138,8 +149,15 @@ var CounterAttacks = map[string]bool{
 	"metal-burst": true,
 	"mirror-coat": true,
 }
+// these ignore power and the damage formula entirely
 var DirectDamageAttacks = map[string]bool{
-	
+	"seismic-toss": true,
+	"night-shade": true,
+	"psywave": true,
+	"super-fang": true,
+	"natures-madness": true,
+	"ruination": true,
+	"endeavor": true,
 }
 var MovesStrongerAgainstMinimized = map[string]bool{
 	"astonish": true,
@@ -276,32 +294,213 @@ var MovesThatDamageThroughProtection = map[string]bool{
 	"hyper-drill": true,
 	"mighty-cleave": true,
 }
+const maxStatStage = 6
+
+// the non-volatile conditions - a pokemon can only have one of these at a time
+var NonVolatileAilments = map[string]bool{
+	"burn": true,
+	"freeze": true,
+	"paralysis": true,
+	"poison": true,
+	"bad-poison": true,
+	"sleep": true,
+}
+var AilmentImmuneTypes = map[string][]string{
+	"burn": {"fire"},
+	"freeze": {"ice"},
+	"paralysis": {"electric"},
+	"poison": {"poison", "steel"},
+	"bad-poison": {"poison", "steel"},
+}
+// the api reports these as plain poison but they inflict toxic's escalating bad poison
+var BadlyPoisoningMoves = map[string]bool{
+	"toxic": true,
+	"poison-fang": true,
+	"malignant-chain": true,
+}
+var ProtectionMoves = map[string]bool{
+	"baneful-bunker": true,
+	"burning-bulwark": true,
+	"detect": true,
+	"kings-shield": true,
+	"obstruct": true,
+	"protect": true,
+	"silk-trap": true,
+	"spiky-shield": true,
+}
+// move targets that never point at the opposing pokemon, so protect does not block them
+var NonOpponentTargets = map[string]bool{
+	"user": true,
+	"users-field": true,
+	"user-and-allies": true,
+	"user-or-ally": true,
+	"ally": true,
+	"all-allies": true,
+	"entire-field": true,
+	"opponents-field": true,
+}
 var MovesWithSpecialTypeEffectiveness = map[string]bool{
 	"flying-press": true,
 	"freeze-dry": true,
 	"thousand-arrows": true,
 }
 
-func BasicDamageCalculator(attacker, defender api.Pokemon, battleContext *api.BattleContext) int {
-	power := 50
-	inner := ((((2 * attacker.Level) / 5 + 2) * power * (attacker.Stats["attack"].StatValue / defender.Stats["defense"].StatValue)) / 50) + 2
-	rng := battleContext.Rng	
-	random := rng.Intn(16) + 85
-	
-	return inner * random / 100
+// TargetsOpponent reports whether a move is aimed at the opposing pokemon (rather than the user or the field)
+func TargetsOpponent(move *api.MoveDetail) bool {
+	return !NonOpponentTargets[move.Target.Name]
 }
+
+// DamageBreakdown records every modifier that went into a single hit so battles can explain their numbers
+type DamageBreakdown struct {
+	Power                     int
+	Category                  string // physical or special
+	AttackStat                float64
+	DefenseStat               float64
+	BaseDamage                int
+	Weather                   float64
+	Terrain                   float64
+	Critical                  bool
+	RandomRoll                int // 85 - 100
+	STAB                      float64
+	Effectiveness             float64
+	Burn                      float64
+	Screen                    float64
+	Damage                    int
+}
+
+// critical hit chance for each crit stage (gen 7+) - a move's Meta.CritRate adds to the stage
+var critChanceByStage = []float64{1.0 / 24, 1.0 / 8, 1.0 / 2, 1}
+
 // the full damage calculator takes into account type effectiveness, critical hit, burn, STAB, Weather among other
+func DamageCalculator(attacker, defender *api.Pokemon, moveInst *api.MoveInstance, typeRelations *api.TypeEffect, battleContext *api.BattleContext) (int, DamageBreakdown) {
+	return hitDamage(attacker, defender, moveInst, typeRelations, battleContext, rngRolls{battleContext.Rng})
+}
 
-func DamageCalculator(attacker, defender api.Pokemon, typeRelations api.TypeEffect, moveIndexChose int) int {
-	return 42
+// damageRolls decides the random parts of a hit - battles roll them, estimates take the average
+type damageRolls interface {
+	critical(critStage int) bool
+	randomRoll() int
+}
+type rngRolls struct {
+	rng            *rand.Rand
+}
+func (r rngRolls) critical(critStage int) bool {
+	return rollCritical(critStage, r.rng)
+}
+func (r rngRolls) randomRoll() int {
+	return r.rng.Intn(16) + 85
+}
+// averageRolls is a non critical hit with the middle random roll
+type averageRolls struct{}
+func (averageRolls) critical(critStage int) bool {
+	return false
+}
+func (averageRolls) randomRoll() int {
+	return 92
 }
-func InitializedSpecialMove(attacker, defender *api.Pokemon, moveInst *api.MoveInstance, battleContext api.BattleContext) int {
-	attackerState := battleContext.PokemonStates[attacker]
-	defenderState := battleContext.PokemonStates[defender]
 
+func hitDamage(attacker, defender *api.Pokemon, moveInst *api.MoveInstance, typeRelations *api.TypeEffect, battleContext *api.BattleContext, rolls damageRolls) (int, DamageBreakdown) {
 	move := moveInst.Detail
+	breakdown := DamageBreakdown{
+		Category: move.DamageClass.Name,
+		STAB: 1,
+		Effectiveness: 1,
+		Burn: 1,
+		Weather: 1,
+		Terrain: 1,
+		Screen: 1,
+	}
+	if move.DamageClass.Name == "status" {
+		return 0, breakdown
+	}
+	breakdown.Power = getMovePower(attacker, defender, moveInst, battleContext)
+	if breakdown.Power <= 0 {
+		return 0, breakdown
+	}
+	attackStatName, defenseStatName := "attack", "defense"
+	if move.DamageClass.Name == "special" {
+		attackStatName, defenseStatName = "special-attack", "special-defense"
+	}
+	breakdown.Critical = rolls.critical(move.Meta.CritRate)
 
-	if ChargingMoves[move.Name] {
+	// a critical hit ignores the attacker's stat drops and the defender's stat boosts
+	attackStage := battleContext.PokemonStates[attacker].StatStages[attackStatName]
+	defenseStage := battleContext.PokemonStates[defender].StatStages[defenseStatName]
+	if breakdown.Critical {
+		attackStage = max(attackStage, 0)
+		defenseStage = min(defenseStage, 0)
+	}
+	breakdown.AttackStat = float64(attacker.Stats[attackStatName].StatValue) * getStatMultiplier(attackStage)
+	breakdown.DefenseStat = float64(defender.Stats[defenseStatName].StatValue) * getStatMultiplier(defenseStage)
+	breakdown.DefenseStat = max(breakdown.DefenseStat * weatherDefenseBoost(defender, defenseStatName, battleContext.Weather.Name), 1)
+
+	levelFactor := float64(2 * attacker.Level / 5 + 2)
+	baseDamage := math.Floor(math.Floor(levelFactor * float64(breakdown.Power) * breakdown.AttackStat / breakdown.DefenseStat) / 50) + 2
+	breakdown.BaseDamage = int(baseDamage)
+
+	// modifiers are applied in game order, flooring after each one
+	damage := baseDamage
+	breakdown.Weather = weatherModifier(move, battleContext.Weather.Name)
+	damage = math.Floor(damage * breakdown.Weather)
+	breakdown.Terrain = terrainModifier(attacker, defender, move, battleContext.Terrain.Name)
+	damage = math.Floor(damage * breakdown.Terrain)
+	if breakdown.Critical {
+		damage = math.Floor(damage * 1.5)
+	}
+	breakdown.RandomRoll = rolls.randomRoll()
+	damage = math.Floor(damage * float64(breakdown.RandomRoll) / 100)
+	if slices.Contains(attacker.Type, move.Type.Name) {
+		breakdown.STAB = 1.5
+		damage = math.Floor(damage * breakdown.STAB)
+	}
+	breakdown.Effectiveness = TypeEffectiveness(typeRelations, move.Type.Name, defender.Type)
+	damage = math.Floor(damage * breakdown.Effectiveness)
+	if move.DamageClass.Name == "physical" && hasAilment(battleContext.PokemonStates[attacker], "burn") {
+		breakdown.Burn = 0.5
+		damage = math.Floor(damage * breakdown.Burn)
+	}
+	breakdown.Screen = screenModifier(defender, move, breakdown.Critical, battleContext)
+	damage = math.Floor(damage * breakdown.Screen)
+	if damage < 1 && breakdown.Effectiveness > 0 {
+		damage = 1
+	}
+	breakdown.Damage = int(damage)
+	return breakdown.Damage, breakdown
+}
+
+// TypeEffectiveness multiplies the attacking type's multiplier against each of the defender's types
+func TypeEffectiveness(typeRelations *api.TypeEffect, moveType string, defenderTypes []string) float64 {
+	effectiveness := 1.0
+	if typeRelations == nil {
+		return effectiveness
+	}
+	relations, ok := typeRelations.TypeMap[moveType]
+	if !ok {
+		return effectiveness
+	}
+	for _, defenderType := range defenderTypes {
+		if multiplier, ok := relations.Effectiveness[defenderType]; ok {
+			effectiveness *= float64(multiplier)
+		}
+	}
+	return effectiveness
+}
+func rollCritical(critStage int, rng *rand.Rand) bool {
+	stage := min(max(critStage, 0), len(critChanceByStage) - 1)
+	return rng.Float64() < critChanceByStage[stage]
+}
+func hasAilment(state api.PokemonBattleState, ailment string) bool {
+	return state.Ailment != nil && state.Ailment.Name == ailment
+}
+// InitializedSpecialMove puts the attacker into the multi-turn state of a charging, semi-invulnerable,
+// rampage or lock-in move the first time it is used. It returns true when the move spends this turn
+// charging and does nothing else
+func InitializedSpecialMove(attacker, defender *api.Pokemon, moveInst *api.MoveInstance, battleContext *api.BattleContext) bool {
+	attackerState := battleContext.PokemonStates[attacker]
+	move := moveInst.Detail
+
+	switch {
+	case ChargingMoves[move.Name] && !(MovesThatChargeInstantlyInSun[move.Name] && battleContext.Weather.Name == "sun"):
 		if MovesWithSemiInvulnerability[move.Name] {
 			attackerState.SemiInvuln = &api.SemiInvulnState{
 				Move: move,
@@ -314,86 +513,126 @@ func InitializedSpecialMove(attacker, defender *api.Pokemon, moveInst *api.MoveI
 				NumTurns: 2,
 				CurrentTurns: 1,
 			}
+			attackerState.ActiveMoveKind = "Charging"
 		}
-
 		attackerState.ActiveMove = move.Name
-		attackerState.ActiveMoveKind = "Charging"
-		
 		battleContext.PokemonStates[attacker] = attackerState
-		return 1
-	}
-	didHit := handleAccuracyCheck(attacker, defender, move, battleContext)
-	if !didHit {
-		return 2
-	}
-	if RampageMoves[move.Name] {
+		return true
+	case RampageMoves[move.Name]:
 		attackerState.Rampaging = &api.RampageState{
 			Move: move,
-			MaxTurns: battleContext.Rng.Intn(2) + 2, // random number either 2 or 3 
-			CurrentTurns: 1,
+			MaxTurns: battleContext.Rng.Intn(2) + 2, // random number either 2 or 3
 			WillConfuse: true,
-
 		}
-		attackerState.ActiveMove = move.Name
 		attackerState.ActiveMoveKind = "Rampage"
-		battleContext.PokemonStates[attacker] = attackerState
-		return 1
-	}
-	if TrappingMoves[move.Name] {
-		defenderState.Trapped = &api.TrappedState{
-			Move: move,
-			CurrentTurns: 1,
-			MaxTurns: battleContext.Rng.Intn(2) + 4,
-		}
-		defenderState.CanFlee = false
-		battleContext.PokemonStates[defender] = defenderState
-		return 1
-	}
-	if LockInMoves[move.Name] {
+	case LockInMoves[move.Name]:
 		attackerState.LockedIn = &api.LockedInState{
 			Move: move,
 			MaxTurns: 5,
-			CurrentTurns: 1,
 		}
-		attackerState.ActiveMove = move.Name
 		attackerState.ActiveMoveKind = "LockedIn"
-		battleContext.PokemonStates[attacker] = attackerState
-		return 1
+	default:
+		return false
 	}
-	return 0
+	attackerState.ActiveMove = move.Name
+	battleContext.PokemonStates[attacker] = attackerState
+	return false
+}
 
+// IsContinuation is true when the move is the next turn of a multi-turn move already in progress
+func IsContinuation(pokemon *api.Pokemon, move *api.MoveDetail, battleContext *api.BattleContext) bool {
+	return battleContext.PokemonStates[pokemon].ActiveMove == move.Name
 }
-func HandleMoveExecution(attacker, defender *api.Pokemon, moveInst *api.MoveInstance, battleContext *api.BattleContext) *MoveOutcome {
-	moveInst.RemainingPP--
+
+// IsChargeTurn is true when using the move now only charges it up
+func IsChargeTurn(pokemon *api.Pokemon, move *api.MoveDetail, battleContext *api.BattleContext) bool {
+	if MovesThatChargeInstantlyInSun[move.Name] && battleContext.Weather.Name == "sun" {
+		return false
+	}
+	return ChargingMoves[move.Name] && !IsContinuation(pokemon, move, battleContext)
+}
+
+// ClearMoveState drops whatever multi-turn move the pokemon was in the middle of - used when the move
+// misses, is blocked, the pokemon can't move or it faints
+func ClearMoveState(pokemon *api.Pokemon, battleContext *api.BattleContext) {
+	state, ok := battleContext.PokemonStates[pokemon]
+	if !ok {
+		return
+	}
+	state.SemiInvuln = nil
+	state.Charging = nil
+	state.LockedIn = nil
+	state.Rampaging = nil
+	state.Recharging = false
+	state.ActiveMove = ""
+	state.ActiveMoveKind = ""
+	battleContext.PokemonStates[pokemon] = state
+}
+
+// advanceMoveState counts a successful hit of a lock-in or rampage move, ending it once it has run
+// its course. A rampage that ends leaves the user confused from fatigue and a move that needs to
+// recharge costs the user its next turn
+func advanceMoveState(attacker *api.Pokemon, move *api.MoveDetail, battleContext *api.BattleContext, moveOutcome *MoveOutcome) {
 	attackerState := battleContext.PokemonStates[attacker]
+	if RechargingMoves[move.Name] {
+		attackerState.Recharging = true
+	}
+	switch attackerState.ActiveMoveKind {
+	case "LockedIn":
+		attackerState.LockedIn.CurrentTurns++
+		if attackerState.LockedIn.CurrentTurns >= attackerState.LockedIn.MaxTurns {
+			battleContext.PokemonStates[attacker] = attackerState
+			ClearMoveState(attacker, battleContext)
+			return
+		}
+	case "Rampage":
+		attackerState.Rampaging.CurrentTurns++
+		if attackerState.Rampaging.CurrentTurns >= attackerState.Rampaging.MaxTurns {
+			if attackerState.Rampaging.WillConfuse && attackerState.Confused == nil {
+				attackerState.Confused = NewConfusion(battleContext.Rng)
+				moveOutcome.UserConfused = true
+			}
+			battleContext.PokemonStates[attacker] = attackerState
+			ClearMoveState(attacker, battleContext)
+			return
+		}
+	}
+	battleContext.PokemonStates[attacker] = attackerState
+}
+
+func HandleMoveExecution(attacker, defender *api.Pokemon, moveInst *api.MoveInstance, battleContext *api.BattleContext) *MoveOutcome {
 	move := moveInst.Detail
 	moveOutcome := &MoveOutcome{
 		TargetStatChanges: make(map[string]int),
 		UserStatChanges: make(map[string]int),
+		Effectiveness: 1,
 	}
 
-	switch attackerState.ActiveMoveKind {
-	// handle the case of a semi-invulnerable or charging move on its turn one no miss is possible
-	case "SemiInvuln":
-		semiInvulnData := attackerState.SemiInvuln
-		if semiInvulnData.Turn == 1 {
-			printSemiInvulnMessage(attacker.Species, defender.Species, move.Name)
-			semiInvulnData.Turn++
-			return moveOutcome
-		}
-	case "Charging":
-		chargingData := attackerState.Charging
-		if chargingData.CurrentTurns < chargingData.NumTurns {
-			printChargingMessage(attacker.Species, move.Name, move.Name)
-			chargingData.CurrentTurns++
+	if !IsContinuation(attacker, move, battleContext) {
+		// only the turn a multi-turn move is chosen uses up pp
+		moveInst.RemainingPP--
+		if InitializedSpecialMove(attacker, defender, moveInst, battleContext) {
+			moveOutcome.Charging = true
+			if MovesWithSemiInvulnerability[move.Name] {
+				moveOutcome.Message = semiInvulnMessage(box.DisplayName(attacker), box.DisplayName(defender), move.Name)
+			} else {
+				moveOutcome.Message = chargingMessage(box.DisplayName(attacker), box.DisplayName(defender), move.Name)
+			}
 			return moveOutcome
 		}
+	} else if kind := battleContext.PokemonStates[attacker].ActiveMoveKind; kind == "SemiInvuln" || kind == "Charging" {
+		// the charged move is released this turn
+		ClearMoveState(attacker, battleContext)
 	}
+
 	didHit := handleAccuracyCheck(attacker, defender, move, *battleContext)
 	if !didHit {
-		return &MoveOutcome{
+		ClearMoveState(attacker, battleContext)
+		missOutcome := &MoveOutcome{
 			Missed: true,
 		}
+		handleCrash(attacker, move, missOutcome)
+		return missOutcome
 	}
 	// if the move did not miss then all moves are handled as they should be
 	moveOutcome.Missed = false
@@ -403,23 +642,30 @@ func HandleMoveExecution(attacker, defender *api.Pokemon, moveInst *api.MoveInst
 	
 	damageEngine(attacker, defender, moveInst, battleContext, moveOutcome)
 	handleRecoil(move.Meta.Drain, moveOutcome)
+	if moveOutcome.Effectiveness == 0 {
+		ClearMoveState(attacker, battleContext)
+		handleCrash(attacker, move, moveOutcome)
+	} else {
+		advanceMoveState(attacker, move, battleContext, moveOutcome)
+	}
 	
 	return moveOutcome
 }
 
-func printSemiInvulnMessage(attackerName, defenderName, moveName string) {
+func semiInvulnMessage(attackerName, defenderName, moveName string) string {
 	switch moveName {
 	case "fly":
-		fmt.Printf("%s flew up high!\n", attackerName)
+		return fmt.Sprintf("%s flew up high!", attackerName)
 	case "bounce":
-		fmt.Printf("%s sprang up!\n", attackerName)
+		return fmt.Sprintf("%s sprang up!", attackerName)
 	case "sky-drop":
-		fmt.Printf("%s took the enemy %s into the sky!", attackerName, defenderName)
+		return fmt.Sprintf("%s took the enemy %s into the sky!", attackerName, defenderName)
 	case "dig":
-		fmt.Printf("%s burrowed its way under the ground!", attackerName)
+		return fmt.Sprintf("%s burrowed its way under the ground!", attackerName)
 	case "dive":
-		fmt.Printf("%s hid underwater!", attackerName)
+		return fmt.Sprintf("%s hid underwater!", attackerName)
 	}
+	return ""
 }
 
 func damageEngine(attacker, defender *api.Pokemon, moveInst *api.MoveInstance, battleContext *api.BattleContext, moveOutcome *MoveOutcome) {
@@ -427,70 +673,408 @@ func damageEngine(attacker, defender *api.Pokemon, moveInst *api.MoveInstance, b
 	moveCategory := moveData.Meta.Category.Name
 	switch moveCategory {
 	default:
-		if FixedDamage[moveData.Name] {
-			if moveData.Name == "sonic-boom" {
-				moveOutcome.Damage = 20
-				return
-			}
-			if moveData.Name == "dragon-rage" {
-				moveOutcome.Damage = 40
+		if FixedDamage[moveData.Name] || DirectDamageAttacks[moveData.Name] {
+			// type immunities still apply but nothing else modifies the damage
+			if TypeEffectiveness(battleContext.TypeChart, moveData.Type.Name, defender.Type) == 0 {
+				moveOutcome.Effectiveness = 0
 				return
 			}
+			moveOutcome.Damage = calcDirectDamage(attacker, defender, moveData, battleContext.Rng)
+			return
 		}
+		breakScreens(attacker, defender, moveData, battleContext, moveOutcome)
 		calcDamage(attacker, defender, moveInst, battleContext, moveOutcome)
-		mutateState()
-		calcStatBoost()
-		calcHeal()
-		calcAilment()
+		mutateState(attacker, defender, moveInst, battleContext, moveOutcome)
+		calcStatBoost(attacker, defender, moveInst, battleContext, moveOutcome)
+		calcHeal(attacker, moveInst, moveOutcome)
+		calcAilment(attacker, defender, moveInst, battleContext, moveOutcome)
+		setField(moveData, battleContext, moveOutcome)
+		setSideConditions(attacker, defender, moveData, battleContext, moveOutcome)
 	}
 
 }
 
 func calcDamage(attacker, defender *api.Pokemon, moveInst *api.MoveInstance, battleContext *api.BattleContext, moveOutcome *MoveOutcome) {
-	getMovePower(attacker, defender, moveInst, battleContext)
-
+	// every hit of a multi-hit move rolls its own critical hit and random factor
+	for i := 0; i < moveOutcome.NumHits; i++ {
+		damage, breakdown := DamageCalculator(attacker, defender, moveInst, battleContext.TypeChart, battleContext)
+		moveOutcome.Damage += damage
+		moveOutcome.Critical = moveOutcome.Critical || breakdown.Critical
+		moveOutcome.Effectiveness = breakdown.Effectiveness
+		moveOutcome.Breakdown = breakdown
+	}
 }
 func getMovePower(attacker, defender *api.Pokemon, moveInst *api.MoveInstance, battleContext *api.BattleContext) int{
-	apiPower := moveInst.Detail.Power
-	if apiPower == 0 {
-		getNonStandardPower(attacker, defender, moveInst, battleContext)
+	move := moveInst.Detail
+	if HasVariablePower(move) {
+		return getNonStandardPower(attacker, defender, moveInst, battleContext)
+	}
+	if lockedIn := battleContext.PokemonStates[attacker].LockedIn; lockedIn != nil && LockInMoves[move.Name] {
+		// rollout and ice-ball double in power with every consecutive hit
+		return move.Power << lockedIn.CurrentTurns
+	}
+	return move.Power
+}
+
+// HasVariablePower reports whether the move's power is worked out in battle rather than read from the api
+func HasVariablePower(move *api.MoveDetail) bool {
+	return PowerBasedOnSpeed[move.Name] || PowerBasedOnWeightDiff[move.Name] || MoreDamageHeavy[move.Name] ||
+		HighDamageHpLevel[move.Name] || CrushGrip[move.Name] || ConditionalPowerDoubling[move.Name] ||
+		MoveAffectedByFriendship[move.Name] || MovesDealingDamageBasedOnIncreasedStatStages[move.Name]
+}
+
+// power-trip gains 20 power for every stage the user has raised, punishment gains 20 for every
+// stage the target has raised up to a cap of 200
+func calcStatStagePower(attacker, defender *api.Pokemon, move *api.MoveDetail, battleContext *api.BattleContext) int {
+	if move.Name == "punishment" {
+		return min(60 + 20 * positiveStages(defender, battleContext), 200)
 	}
-	return 42
+	return 20 + 20 * positiveStages(attacker, battleContext)
 }
 func getNonStandardPower(attacker, defender *api.Pokemon, moveInst *api.MoveInstance, battleContext *api.BattleContext) int{
-	moveName := moveInst.Detail.Name
-	if strings.HasPrefix(moveInst.Detail.Meta.Category.Name, "damage") {
-		// these are damage dealing moves that do not have a fixed power
-		if moveName == "gyro-ball" {
-			return int(calcGyroBallPower(attacker, defender, battleContext))
+	move := moveInst.Detail
+	moveName := move.Name
+	switch {
+	case moveName == "gyro-ball":
+		return int(calcGyroBallPower(attacker, defender, battleContext))
+	case moveName == "electro-ball":
+		return int(calcElectroBallPower(attacker, defender, battleContext))
+	case PowerBasedOnWeightDiff[moveName]:
+		return int(calcPowerBasedOnWeightDiff(attacker, defender, battleContext))
+	case MoreDamageHeavy[moveName]:
+		return calcPowerBasedOnWeight(defender)
+	case moveName == "flail" || moveName == "reversal":
+		return calcFlailPower(attacker)
+	case HighDamageHpLevel[moveName]:
+		// eruption, water-spout and dragon-energy scale down with the user's remaining hp
+		return max(150 * attacker.CurrHp / maxHp(attacker), 1)
+	case CrushGrip[moveName]:
+		// these scale with the target's remaining hp instead
+		maxPower := 120
+		if moveName == "hard-press" {
+			maxPower = 100
 		}
-		if moveName == "electro-ball" {
-			return int(calcElectroBallPower(attacker, defender, battleContext))
+		return max(maxPower * defender.CurrHp / maxHp(defender), 1)
+	case ConditionalPowerDoubling[moveName]:
+		return calcConditionalDoublePower(defender, move, battleContext)
+	case MoveAffectedByFriendship[moveName]:
+		return calcFriendshipPower(attacker, moveName)
+	case MovesDealingDamageBasedOnIncreasedStatStages[moveName]:
+		return calcStatStagePower(attacker, defender, move, battleContext)
+	}
+	return move.Power
+}
+func maxHp(pokemon *api.Pokemon) int {
+	return max(pokemon.Stats["hp"].StatValue, 1)
+}
+
+// low-kick and grass-knot hit harder the heavier the target is (weight in hectograms)
+func calcPowerBasedOnWeight(defender *api.Pokemon) int {
+	switch weight := defender.Weight; {
+	case weight < 100:
+		return 20
+	case weight < 250:
+		return 40
+	case weight < 500:
+		return 60
+	case weight < 1000:
+		return 80
+	case weight < 2000:
+		return 100
+	}
+	return 120
+}
+
+// flail and reversal hit harder the less hp the user has left, in brackets of 48ths of max hp
+func calcFlailPower(attacker *api.Pokemon) int {
+	switch hpRatio := 48 * attacker.CurrHp / maxHp(attacker); {
+	case hpRatio <= 1:
+		return 200
+	case hpRatio <= 4:
+		return 150
+	case hpRatio <= 9:
+		return 100
+	case hpRatio <= 16:
+		return 80
+	case hpRatio <= 32:
+		return 40
+	}
+	return 20
+}
+
+// hex doubles against a statused target, venoshock against a poisoned one and brine
+// against a target at half hp or less
+func calcConditionalDoublePower(defender *api.Pokemon, move *api.MoveDetail, battleContext *api.BattleContext) int {
+	power := move.Power
+	if power == 0 {
+		power = 65
+	}
+	defenderState := battleContext.PokemonStates[defender]
+	doubled := false
+	switch move.Name {
+	case "hex":
+		doubled = defenderState.Ailment != nil
+	case "venoshock":
+		doubled = hasAilment(defenderState, "poison") || hasAilment(defenderState, "bad-poison")
+	case "brine":
+		doubled = defender.CurrHp * 2 <= maxHp(defender)
+	}
+	if doubled {
+		return power * 2
+	}
+	return power
+}
+
+// return and the partner pikachu / eevee moves grow with friendship, frustration with the lack of it
+func calcFriendshipPower(attacker *api.Pokemon, moveName string) int {
+	friendship := min(max(attacker.Friendship, 0), 255)
+	if moveName == "frustration" {
+		friendship = 255 - friendship
+	}
+	return max(friendship * 10 / 25, 1)
+}
+
+// calcDirectDamage handles the moves whose damage doesn't come from the formula - sonic-boom and
+// dragon-rage deal a flat amount, seismic-toss and night-shade deal the user's level, super-fang halves
+// the target's hp and endeavor cuts it down to the user's
+func calcDirectDamage(attacker, defender *api.Pokemon, move *api.MoveDetail, rng *rand.Rand) int {
+	switch move.Name {
+	case "sonic-boom":
+		return 20
+	case "dragon-rage":
+		return 40
+	case "seismic-toss", "night-shade":
+		return attacker.Level
+	case "psywave":
+		return max(attacker.Level * (rng.Intn(101) + 50) / 100, 1)
+	case "super-fang", "natures-madness", "ruination":
+		return max(defender.CurrHp / 2, 1)
+	case "endeavor":
+		return max(defender.CurrHp - attacker.CurrHp, 0)
+	}
+	return 0
+}
+// mutateState applies the volatile conditions a move leaves on its target - confusion from moves
+// like confuse-ray or psybeam's secondary effect and the binding of trapping moves like wrap
+func mutateState(attacker, defender *api.Pokemon, moveInst *api.MoveInstance, battleContext *api.BattleContext, moveOutcome *MoveOutcome) {
+	move := moveInst.Detail
+	isStatusMove := move.DamageClass.Name == "status"
+	if !isStatusMove && (moveOutcome.Effectiveness == 0 || moveOutcome.Damage >= defender.CurrHp) {
+		return
+	}
+	defenderState := battleContext.PokemonStates[defender]
+
+	if TrappingMoves[move.Name] || move.Meta.Ailment.Name == "trap" {
+		if defenderState.Trapped == nil {
+			defenderState.Trapped = &api.TrappedState{
+				Move: move,
+				MaxTurns: battleContext.Rng.Intn(2) + 4,
+			}
+			defenderState.CanFlee = false
+			battleContext.PokemonStates[defender] = defenderState
+			moveOutcome.CausedStatus = "trap"
 		}
-		if PowerBasedOnWeightDiff[moveName] {
-			return int(calcPowerBasedOnWeightDiff(attacker, defender, battleContext))
+		return
+	}
+	if move.Meta.Ailment.Name != "confusion" {
+		return
+	}
+	if defenderState.Confused != nil || TerrainBlocksAilment(defender, "confusion", battleContext.Terrain.Name) {
+		if isStatusMove {
+			moveOutcome.StatusFailed = true
 		}
+		return
+	}
+	chance := move.Meta.AilmentChance
+	if chance == 0 && isStatusMove {
+		chance = 100
 	}
-	return int(42)
+	if battleContext.Rng.Intn(100) >= chance {
+		return
+	}
+	defenderState.Confused = NewConfusion(battleContext.Rng)
+	battleContext.PokemonStates[defender] = defenderState
+	moveOutcome.CausedStatus = "confusion"
 }
-func mutateState() {
+
+// NewConfusion lasts 2-5 turns, counted down each time the pokemon tries to move
+func NewConfusion(rng *rand.Rand) *api.ConfusedState {
+	return &api.ConfusedState{MaxTurns: rng.Intn(4) + 2}
 }
-func calcStatBoost() {
+
+// ConfusionDamage is the hit a confused pokemon deals itself: a typeless 40 power physical attack
+// using its own attack and defense that can't be a critical hit and ignores STAB and type matchups
+func ConfusionDamage(pokemon *api.Pokemon, battleContext *api.BattleContext) int {
+	const confusionPower = 40
+	state := battleContext.PokemonStates[pokemon]
+	attackStat := float64(pokemon.Stats["attack"].StatValue) * getStatMultiplier(state.StatStages["attack"])
+	defenseStat := max(float64(pokemon.Stats["defense"].StatValue) * getStatMultiplier(state.StatStages["defense"]), 1)
+
+	levelFactor := float64(2 * pokemon.Level / 5 + 2)
+	damage := math.Floor(math.Floor(levelFactor * confusionPower * attackStat / defenseStat) / 50) + 2
+	damage = math.Floor(damage * float64(battleContext.Rng.Intn(16) + 85) / 100)
+	if hasAilment(state, "burn") {
+		damage = math.Floor(damage * 0.5)
+	}
+	return max(int(damage), 1)
 }
-func calcHeal() {
+// calcStatBoost applies the move's stat changes. Damaging moves that raise (ancient-power) or drop
+// (close-combat) stats act on the user and damage+lower moves act on the target, for pure stat moves
+// the move's target decides between swords-dance on the user and growl on the foe
+func calcStatBoost(attacker, defender *api.Pokemon, moveInst *api.MoveInstance, battleContext *api.BattleContext, moveOutcome *MoveOutcome) {
+	move := moveInst.Detail
+	if len(move.StatChange) == 0 {
+		return
+	}
+	isStatusMove := move.DamageClass.Name == "status"
+	if !isStatusMove && moveOutcome.Effectiveness == 0 {
+		return
+	}
+	target, changes := defender, moveOutcome.TargetStatChanges
+	if StatChangesTargetUser(move) {
+		target, changes = attacker, moveOutcome.UserStatChanges
+	} else if !isStatusMove && moveOutcome.Damage >= defender.CurrHp {
+		return
+	}
+	// a stat chance of 0 means the change always happens
+	if chance := move.Meta.StatChance; chance > 0 && chance < 100 && battleContext.Rng.Intn(100) >= chance {
+		return
+	}
+	for _, statChange := range move.StatChange {
+		changes[statChange.Stat.Name] = ApplyStatStage(target, battleContext, statChange.Stat.Name, statChange.Change)
+	}
 }
-func calcAilment() {
+
+// StatChangesTargetUser reports whether the move's stat changes land on the user rather than the target
+func StatChangesTargetUser(move *api.MoveDetail) bool {
+	switch move.Meta.Category.Name {
+	case "damage+raise":
+		return true
+	case "damage+lower":
+		return false
+	}
+	return !TargetsOpponent(move)
+}
+
+// ApplyStatStage moves one stat stage by change, clamped to -6..+6, and returns how far it actually
+// moved - 0 means it was already at the limit. Accuracy and evasion live on the pokemon itself
+func ApplyStatStage(pokemon *api.Pokemon, battleContext *api.BattleContext, stat string, change int) int {
+	clamp := func(stage int) int {
+		return min(max(stage + change, -maxStatStage), maxStatStage)
+	}
+	switch stat {
+	case "accuracy":
+		before := pokemon.AccuracyStage
+		pokemon.AccuracyStage = clamp(before)
+		return pokemon.AccuracyStage - before
+	case "evasion":
+		before := pokemon.EvasionStage
+		pokemon.EvasionStage = clamp(before)
+		return pokemon.EvasionStage - before
+	}
+	state := battleContext.PokemonStates[pokemon]
+	if state.StatStages == nil {
+		state.StatStages = make(map[string]int)
+	}
+	before := state.StatStages[stat]
+	state.StatStages[stat] = clamp(before)
+	battleContext.PokemonStates[pokemon] = state
+	return state.StatStages[stat] - before
+}
+
+// positiveStages sums every stage the pokemon has raised above 0, accuracy and evasion included
+func positiveStages(pokemon *api.Pokemon, battleContext *api.BattleContext) int {
+	total := max(pokemon.AccuracyStage, 0) + max(pokemon.EvasionStage, 0)
+	for _, stage := range battleContext.PokemonStates[pokemon].StatStages {
+		total += max(stage, 0)
+	}
+	return total
+}
+// calcHeal works out how much of the user's max hp a move like recover or roost restores. A negative
+// Meta.Healing is an hp cost instead, like steel-beam's. Healing at full hp fails
+func calcHeal(attacker *api.Pokemon, moveInst *api.MoveInstance, moveOutcome *MoveOutcome) {
+	move := moveInst.Detail
+	if move.Meta.Healing == 0 {
+		return
+	}
+	maxHp := attacker.Stats["hp"].StatValue
+	if move.Meta.Healing > 0 && attacker.CurrHp >= maxHp {
+		if move.DamageClass.Name == "status" {
+			moveOutcome.StatusFailed = true
+		}
+		return
+	}
+	healing := maxHp * move.Meta.Healing / 100
+	if healing == 0 {
+		healing = 1
+		if move.Meta.Healing < 0 {
+			healing = -1
+		}
+	}
+	moveOutcome.Healing = healing
+}
+// calcAilment rolls the move's Meta.Ailment against the defender and writes it into the defender's
+// battle state - status moves (category "ailment") always try to apply it, damaging moves roll AilmentChance
+func calcAilment(attacker, defender *api.Pokemon, moveInst *api.MoveInstance, battleContext *api.BattleContext, moveOutcome *MoveOutcome) {
+	move := moveInst.Detail
+	ailment := move.Meta.Ailment.Name
+	if BadlyPoisoningMoves[move.Name] {
+		ailment = "bad-poison"
+	}
+	if !NonVolatileAilments[ailment] {
+		return
+	}
+	isStatusMove := move.DamageClass.Name == "status"
+	defenderState := battleContext.PokemonStates[defender]
+
+	if !isStatusMove && (moveOutcome.Effectiveness == 0 || moveOutcome.Damage >= defender.CurrHp) {
+		// the move didn't connect or the defender is about to faint so there is nothing to afflict
+		return
+	}
+	if defenderState.Ailment != nil || IsImmuneToAilment(defender, ailment) || TerrainBlocksAilment(defender, ailment, battleContext.Terrain.Name) || (isStatusMove && TypeEffectiveness(battleContext.TypeChart, move.Type.Name, defender.Type) == 0) {
+		if isStatusMove {
+			moveOutcome.StatusFailed = true
+		}
+		return
+	}
+	chance := move.Meta.AilmentChance
+	if chance == 0 && (isStatusMove || move.Meta.Category.Name == "ailment") {
+		chance = 100
+	}
+	if battleContext.Rng.Intn(100) >= chance {
+		return
+	}
+	newAilment := &api.AilmentState{Name: ailment}
+	if ailment == "sleep" {
+		newAilment.MaxTurns = battleContext.Rng.Intn(3) + 1
+		moveOutcome.StatusDuration = newAilment.MaxTurns
+	}
+	defenderState.Ailment = newAilment
+	battleContext.PokemonStates[defender] = defenderState
+	moveOutcome.CausedStatus = ailment
+}
+
+// IsImmuneToAilment covers the type based status immunities - fire can't be burned, electric can't be
+// paralyzed, ice can't be frozen and poison or steel types can't be poisoned
+func IsImmuneToAilment(pokemon *api.Pokemon, ailment string) bool {
+	for _, immuneType := range AilmentImmuneTypes[ailment] {
+		if slices.Contains(pokemon.Type, immuneType) {
+			return true
+		}
+	}
+	return false
 }
 func calcGyroBallPower(attacker, defender *api.Pokemon, battleContext *api.BattleContext) float64{
-	attackerSpeed := calcEffectiveStat(attacker, battleContext, "speed")
-	defenderSpeed := calcEffectiveStat(defender, battleContext, "speed")
+	// a pokemon with no speed at all still counts as 1 so the power stays finite, capped at 150
+	attackerSpeed := max(CalcEffectiveStat(attacker, battleContext, "speed"), 1)
+	defenderSpeed := CalcEffectiveStat(defender, battleContext, "speed")
 
 	damageFormula := (25 * defenderSpeed / attackerSpeed) + 1
 	return min(150.0, damageFormula)
 }
 func calcElectroBallPower(attacker, defender *api.Pokemon, battleContext *api.BattleContext) float64{
-	attackerSpeed := calcEffectiveStat(attacker, battleContext, "speed")
-	defenderSpeed := calcEffectiveStat(defender, battleContext, "speed")
+	attackerSpeed := CalcEffectiveStat(attacker, battleContext, "speed")
+	defenderSpeed := CalcEffectiveStat(defender, battleContext, "speed")
 
 	if attackerSpeed >= 4 * defenderSpeed {
 		return 150
@@ -524,54 +1108,56 @@ func calcPowerBasedOnWeightDiff(attacker, defender *api.Pokemon, battleContext *
 	}
 	return 40
 }
-func calcEffectiveStat(pokemon *api.Pokemon, battleContext *api.BattleContext, stat string) float64 {
+// CalcEffectiveStat applies stat stages (and paralysis for speed) to a pokemon's stat
+func CalcEffectiveStat(pokemon *api.Pokemon, battleContext *api.BattleContext, stat string) float64 {
 	ailmentMod := 1.0
 	multiplier := getStatMultiplier(battleContext.PokemonStates[pokemon].StatStages[stat])
-	if stat == "speed" && battleContext.PokemonStates[pokemon].Ailment.Name == "paralysis" {
+	if stat == "speed" && hasAilment(battleContext.PokemonStates[pokemon], "paralysis") {
 		ailmentMod = 0.5
 	}
 	return float64(pokemon.Stats[stat].StatValue) * multiplier * ailmentMod
 }
 
-func printChargingMessage(attackerName, defenderName, moveName string) {
+func chargingMessage(attackerName, defenderName, moveName string) string {
 	switch moveName {
 	case "solar-beam":
-		fmt.Printf("%s absorbed light!\n", attackerName)
+		return fmt.Sprintf("%s absorbed light!", attackerName)
 	case "skull-bash":
-		fmt.Printf("%s lowered its head!\n", attackerName)
+		return fmt.Sprintf("%s lowered its head!", attackerName)
 	case "sky-attack":
-		fmt.Printf("%s became cloaked in a harsh light!\n", attackerName)
+		return fmt.Sprintf("%s became cloaked in a harsh light!", attackerName)
 	case "meteor-beam":
-		fmt.Printf("%s is overflowing with space power!\n", attackerName)
+		return fmt.Sprintf("%s is overflowing with space power!", attackerName)
 	case "razor-wind":
-		fmt.Printf("%s made a whirlwind!\n", attackerName)
+		return fmt.Sprintf("%s made a whirlwind!", attackerName)
 	case "bounce":
-		fmt.Printf("%s sprang up!\n", attackerName)
+		return fmt.Sprintf("%s sprang up!", attackerName)
 	case "dig":
-		fmt.Printf("%s dug a hole!\n", attackerName)
+		return fmt.Sprintf("%s dug a hole!", attackerName)
 	case "dive":
-		fmt.Printf("%s hid underwater!\n", attackerName)
+		return fmt.Sprintf("%s hid underwater!", attackerName)
 	case "phantom-force":
-		fmt.Printf("%s vanished instantly!\n", attackerName)
+		return fmt.Sprintf("%s vanished instantly!", attackerName)
 	case "electro-shot":
-		fmt.Printf("%s absorbed electricity!\n", attackerName)
+		return fmt.Sprintf("%s absorbed electricity!", attackerName)
 	case "fly":
-		fmt.Printf("%s flew up high!\n", attackerName)
+		return fmt.Sprintf("%s flew up high!", attackerName)
 	case "shadow-force":
-		fmt.Printf("%s vanished instantly!\n", attackerName)
+		return fmt.Sprintf("%s vanished instantly!", attackerName)
 	case "freeze-shock":
-		fmt.Printf("%s became cloaked in a freezing light!\n", attackerName)
+		return fmt.Sprintf("%s became cloaked in a freezing light!", attackerName)
 	case "sky-drop":
-		fmt.Printf("%s took the enemy %s into the sky!\n", attackerName, defenderName)
+		return fmt.Sprintf("%s took the enemy %s into the sky!", attackerName, defenderName)
 	case "solar-blade":
-		fmt.Printf("%s absorbed light!\n", attackerName)
+		return fmt.Sprintf("%s absorbed light!", attackerName)
 	case "geomancy":
-		fmt.Printf("%s is absorbing power!\n", attackerName)
+		return fmt.Sprintf("%s is absorbing power!", attackerName)
 	case "ice-burn":
-		fmt.Printf("%s became cloaked in freezing air!\n", attackerName)
+		return fmt.Sprintf("%s became cloaked in freezing air!", attackerName)
 	case "focus-punch":
-		fmt.Printf("%s is tightening its focus!\n", attackerName)
+		return fmt.Sprintf("%s is tightening its focus!", attackerName)
 	}
+	return ""
 }
 
 
@@ -604,14 +1190,43 @@ func handleFlinch(flinchChance int, rng *rand.Rand, moveOutcome *MoveOutcome) {
 	moveOutcome.Flinched = causedFlinch
 }
 
+// handleRecoil turns Meta.Drain into the share of the damage dealt that comes back to the user:
+// recoil moves (negative drain) hurt it and drain moves (negative multiplier) heal it
 func handleRecoil(recoilPercent int, moveOutcome *MoveOutcome) {
 	moveOutcome.RecoilDamageMultiplier = -float32(recoilPercent) / 100
 }
+
+// RecoilFromDamage is the hp the user loses (or gains when negative) after dealing damage,
+// never rounding down to nothing
+func RecoilFromDamage(dealt int, multiplier float32) int {
+	if dealt <= 0 || multiplier == 0 {
+		return 0
+	}
+	recoil := int(float32(dealt) * multiplier)
+	if recoil == 0 {
+		if multiplier > 0 {
+			return 1
+		}
+		return -1
+	}
+	return recoil
+}
+
+// handleCrash hurts a jump-kick user for half its max hp when the kick misses or has no effect
+func handleCrash(attacker *api.Pokemon, move *api.MoveDetail, moveOutcome *MoveOutcome) {
+	if CrashDamageIfMiss[move.Name] {
+		moveOutcome.CrashDamage = max(attacker.Stats["hp"].StatValue / 2, 1)
+	}
+}
 func handleAccuracyCheck(attacker, defender *api.Pokemon, move *api.MoveDetail, battleContext api.BattleContext) bool {
 	if slices.Contains(attacker.Type, "poison") && move.Name == "toxic" {
 		// toxic always hits when used by a poison type
 		return true
 	}
+	if !TargetsOpponent(move) {
+		// moves on the user or the field can't miss, even with the foe out of reach
+		return true
+	}
 
 	if semiInvulnData := battleContext.PokemonStates[defender].SemiInvuln; semiInvulnData != nil {
 		semiInvulnMove := semiInvulnData.Move.Name
@@ -621,16 +1236,24 @@ func handleAccuracyCheck(attacker, defender *api.Pokemon, move *api.MoveDetail,
 		return false
 	}
 
-	if move.Accuracy == 0 {
+	accuracy, exempt := moveAccuracy(attacker, move, defender, battleContext.Weather.Name)
+	if exempt {
 		return true // moves exempt from normal accuracy calculation e.g. swift and aerial ace
 	}
-
-	accuracy := min(float64(move.Accuracy) * getAccuracyMultiplier(attacker.AccuracyStage) / getAccuracyMultiplier(defender.EvasionStage), 100)
 	rng := battleContext.Rng
 
 	return float64(rng.Intn(100)) < accuracy
 
 }
+// moveAccuracy is the percent chance the move hits after weather and accuracy / evasion stages,
+// exempt is true for moves that skip the accuracy check altogether
+func moveAccuracy(attacker *api.Pokemon, move *api.MoveDetail, defender *api.Pokemon, weather string) (float64, bool) {
+	accuracy := weatherAccuracy(move, weather)
+	if accuracy == 0 {
+		return 100, true
+	}
+	return min(float64(accuracy) * getAccuracyMultiplier(attacker.AccuracyStage) / getAccuracyMultiplier(defender.EvasionStage), 100), false
+}
 func getAccuracyMultiplier(stage int) float64 {
     switch stage {
     case -6:
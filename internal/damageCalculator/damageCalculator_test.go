@@ -0,0 +1,320 @@
+package damageCalculator
+
+import (
+	"math"
+	"math/rand"
+	"testing"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/fixtures"
+)
+
+func testTypeChart() *api.TypeEffect {
+	return &api.TypeEffect{
+		TypeMap: map[string]api.Relations{
+			"fire": {Effectiveness: map[string]float32{"grass": 2, "water": 0.5, "fire": 0.5}},
+			"normal": {Effectiveness: map[string]float32{"ghost": 0, "rock": 0.5}},
+			"ground": {Effectiveness: map[string]float32{"flying": 0, "electric": 2}},
+		},
+	}
+}
+func testBattleContext(seed int64, pokemon ...*api.Pokemon) *api.BattleContext {
+	battleContext := &api.BattleContext{
+		Rng: rand.New(rand.NewSource(seed)),
+		PokemonStates: make(map[*api.Pokemon]api.PokemonBattleState),
+		TypeChart: testTypeChart(),
+	}
+	for _, p := range pokemon {
+		battleContext.PokemonStates[p] = api.PokemonBattleState{StatStages: make(map[string]int)}
+	}
+	return battleContext
+}
+
+func TestDamageCalculator(t *testing.T) {
+	cases := []struct {
+		name                string
+		attackerTypes       []string
+		defenderTypes       []string
+		move                *api.MoveInstance
+		attackerAilment     string
+		attackStage         int
+		expectedBase        int
+		expectedSTAB        float64
+		expectedEffect      float64
+		expectedBurn        float64
+	}{
+		{
+			name: "neutral physical",
+			attackerTypes: []string{"water"},
+			defenderTypes: []string{"normal"},
+			move: fixtures.Instance(fixtures.Move("fire-punch", "fire", "physical", 80, 100)),
+			expectedBase: 37,
+			expectedSTAB: 1,
+			expectedEffect: 1,
+			expectedBurn: 1,
+		},
+		{
+			name: "stab super effective special",
+			attackerTypes: []string{"fire"},
+			defenderTypes: []string{"grass"},
+			move: fixtures.Instance(fixtures.Move("flamethrower", "fire", "special", 80, 100)),
+			expectedBase: 37,
+			expectedSTAB: 1.5,
+			expectedEffect: 2,
+			expectedBurn: 1,
+		},
+		{
+			name: "dual type resistance",
+			attackerTypes: []string{"normal"},
+			defenderTypes: []string{"water", "fire"},
+			move: fixtures.Instance(fixtures.Move("ember", "fire", "special", 40, 100)),
+			expectedBase: 19,
+			expectedSTAB: 1,
+			expectedEffect: 0.25,
+			expectedBurn: 1,
+		},
+		{
+			name: "burn halves physical damage",
+			attackerTypes: []string{"normal"},
+			defenderTypes: []string{"normal"},
+			move: fixtures.Instance(fixtures.Move("body-slam", "normal", "physical", 85, 100)),
+			attackerAilment: "burn",
+			expectedBase: 39,
+			expectedSTAB: 1.5,
+			expectedEffect: 1,
+			expectedBurn: 0.5,
+		},
+		{
+			name: "attack stage applies",
+			attackerTypes: []string{"water"},
+			defenderTypes: []string{"normal"},
+			move: fixtures.Instance(fixtures.Move("fire-punch", "fire", "physical", 80, 100)),
+			attackStage: 2,
+			expectedBase: 72,
+			expectedSTAB: 1,
+			expectedEffect: 1,
+			expectedBurn: 1,
+		},
+	}
+	for _, c := range cases {
+		for seed := int64(0); seed < 50; seed++ {
+			attacker := fixtures.Pokemon("attacker", c.attackerTypes, 100, 100)
+			defender := fixtures.Pokemon("defender", c.defenderTypes, 100, 100)
+			battleContext := testBattleContext(seed, attacker, defender)
+			attackerState := battleContext.PokemonStates[attacker]
+			attackerState.StatStages["attack"] = c.attackStage
+			if c.attackerAilment != "" {
+				attackerState.Ailment = &api.AilmentState{Name: c.attackerAilment}
+			}
+			battleContext.PokemonStates[attacker] = attackerState
+
+			damage, breakdown := DamageCalculator(attacker, defender, c.move, battleContext.TypeChart, battleContext)
+			base := c.expectedBase
+			if breakdown.Critical {
+				// the stage boost is kept on a critical hit so the base is unchanged
+				base = int(math.Floor(float64(c.expectedBase) * 1.5))
+			}
+			if breakdown.BaseDamage != c.expectedBase || breakdown.STAB != c.expectedSTAB || breakdown.Effectiveness != c.expectedEffect || breakdown.Burn != c.expectedBurn {
+				t.Errorf("%s: Got %+v expected base %d, stab %v, effectiveness %v, burn %v", c.name, breakdown, c.expectedBase, c.expectedSTAB, c.expectedEffect, c.expectedBurn)
+				break
+			}
+			if breakdown.RandomRoll < 85 || breakdown.RandomRoll > 100 {
+				t.Errorf("%s: random roll %d outside 85-100", c.name, breakdown.RandomRoll)
+			}
+			expected := math.Floor(float64(base) * float64(breakdown.RandomRoll) / 100)
+			expected = math.Floor(expected * c.expectedSTAB)
+			expected = math.Floor(expected * c.expectedEffect)
+			expected = math.Floor(expected * c.expectedBurn)
+			expected = max(expected, 1)
+			if damage != int(expected) {
+				t.Errorf("%s (seed %d): Got %d damage expected %d", c.name, seed, damage, int(expected))
+			}
+		}
+	}
+}
+
+func TestDamageCalculatorImmunityAndStatus(t *testing.T) {
+	attacker := fixtures.Pokemon("attacker", []string{"normal"}, 100, 100)
+	defender := fixtures.Pokemon("defender", []string{"ghost"}, 100, 100)
+	battleContext := testBattleContext(1, attacker, defender)
+
+	damage, _ := DamageCalculator(attacker, defender, fixtures.Instance(fixtures.Move("tackle", "normal", "physical", 40, 100)), battleContext.TypeChart, battleContext)
+	if damage != 0 {
+		t.Errorf("Got %d damage expected ghost to be immune to normal moves", damage)
+	}
+	damage, _ = DamageCalculator(attacker, defender, fixtures.Instance(fixtures.Move("growl", "normal", "status", 0, 100)), battleContext.TypeChart, battleContext)
+	if damage != 0 {
+		t.Errorf("Got %d damage expected status moves to deal none", damage)
+	}
+}
+
+func TestStatStagePower(t *testing.T) {
+	attacker := fixtures.Pokemon("attacker", []string{"dark"}, 100, 100)
+	defender := fixtures.Pokemon("defender", []string{"normal"}, 100, 100)
+	battleContext := testBattleContext(1, attacker, defender)
+	ApplyStatStage(attacker, battleContext, "attack", 2)
+	ApplyStatStage(attacker, battleContext, "speed", 1)
+	ApplyStatStage(attacker, battleContext, "defense", -1)
+	ApplyStatStage(defender, battleContext, "evasion", 6)
+	ApplyStatStage(defender, battleContext, "special-attack", 6)
+
+	cases := []struct {
+		move            string
+		power           int
+		expectedPower   int
+	}{
+		{move: "power-trip", power: 20, expectedPower: 80},
+		{move: "punishment", power: 60, expectedPower: 200},
+	}
+	for _, c := range cases {
+		_, breakdown := DamageCalculator(attacker, defender, fixtures.Instance(fixtures.Move(c.move, "dark", "physical", c.power, 100)), battleContext.TypeChart, battleContext)
+		if breakdown.Power != c.expectedPower {
+			t.Errorf("%s: Got power %d expected %d", c.move, breakdown.Power, c.expectedPower)
+		}
+	}
+	if applied := ApplyStatStage(defender, battleContext, "evasion", 1); applied != 0 || defender.EvasionStage != 6 {
+		t.Errorf("Got %d applied expected evasion to stay clamped at +6", applied)
+	}
+}
+
+func TestVariablePower(t *testing.T) {
+	cases := []struct {
+		move            string
+		power           int
+		setup           func(attacker, defender *api.Pokemon, battleContext *api.BattleContext)
+		expectedPower   int
+	}{
+		{move: "eruption", power: 150, expectedPower: 150},
+		{move: "eruption", power: 150, setup: func(a, d *api.Pokemon, bc *api.BattleContext) { a.CurrHp = 33 }, expectedPower: 49},
+		{move: "water-spout", power: 150, setup: func(a, d *api.Pokemon, bc *api.BattleContext) { a.CurrHp = 0 }, expectedPower: 1},
+		{move: "flail", expectedPower: 20},
+		{move: "flail", setup: func(a, d *api.Pokemon, bc *api.BattleContext) { a.CurrHp = 2 }, expectedPower: 200},
+		{move: "reversal", setup: func(a, d *api.Pokemon, bc *api.BattleContext) { a.CurrHp = 30 }, expectedPower: 80},
+		{move: "low-kick", setup: func(a, d *api.Pokemon, bc *api.BattleContext) { d.Weight = 69 }, expectedPower: 20},
+		{move: "low-kick", setup: func(a, d *api.Pokemon, bc *api.BattleContext) { d.Weight = 4600 }, expectedPower: 120},
+		{move: "grass-knot", setup: func(a, d *api.Pokemon, bc *api.BattleContext) { d.Weight = 905 }, expectedPower: 80},
+		{move: "crush-grip", expectedPower: 120},
+		{move: "wring-out", setup: func(a, d *api.Pokemon, bc *api.BattleContext) { d.CurrHp = 25 }, expectedPower: 30},
+		{move: "hard-press", setup: func(a, d *api.Pokemon, bc *api.BattleContext) { d.CurrHp = 50 }, expectedPower: 50},
+		{move: "hex", power: 65, expectedPower: 65},
+		{move: "hex", power: 65, setup: func(a, d *api.Pokemon, bc *api.BattleContext) { setAilment(bc, d, "paralysis") }, expectedPower: 130},
+		{move: "venoshock", power: 65, setup: func(a, d *api.Pokemon, bc *api.BattleContext) { setAilment(bc, d, "burn") }, expectedPower: 65},
+		{move: "venoshock", power: 65, setup: func(a, d *api.Pokemon, bc *api.BattleContext) { setAilment(bc, d, "bad-poison") }, expectedPower: 130},
+		{move: "brine", power: 65, setup: func(a, d *api.Pokemon, bc *api.BattleContext) { d.CurrHp = 51 }, expectedPower: 65},
+		{move: "brine", power: 65, setup: func(a, d *api.Pokemon, bc *api.BattleContext) { d.CurrHp = 50 }, expectedPower: 130},
+		{move: "return", setup: func(a, d *api.Pokemon, bc *api.BattleContext) { a.Friendship = 255 }, expectedPower: 102},
+		{move: "return", setup: func(a, d *api.Pokemon, bc *api.BattleContext) { a.Friendship = 70 }, expectedPower: 28},
+		{move: "frustration", setup: func(a, d *api.Pokemon, bc *api.BattleContext) { a.Friendship = 0 }, expectedPower: 102},
+		{move: "veevee-volley", setup: func(a, d *api.Pokemon, bc *api.BattleContext) { a.Friendship = 0 }, expectedPower: 1},
+		{move: "gyro-ball", setup: func(a, d *api.Pokemon, bc *api.BattleContext) { a.Stats["speed"] = api.BundleStats{StatValue: 25} }, expectedPower: 101},
+		{move: "gyro-ball", setup: func(a, d *api.Pokemon, bc *api.BattleContext) { a.Stats["speed"] = api.BundleStats{StatValue: 0} }, expectedPower: 150},
+		{move: "gyro-ball", setup: func(a, d *api.Pokemon, bc *api.BattleContext) { a.Stats["speed"], d.Stats["speed"] = api.BundleStats{}, api.BundleStats{} }, expectedPower: 1},
+		{move: "electro-ball", setup: func(a, d *api.Pokemon, bc *api.BattleContext) { a.Stats["speed"] = api.BundleStats{StatValue: 300} }, expectedPower: 120},
+		{move: "heavy-slam", setup: func(a, d *api.Pokemon, bc *api.BattleContext) { a.Weight, d.Weight = 4000, 800 }, expectedPower: 120},
+	}
+	for _, c := range cases {
+		attacker := fixtures.Pokemon("attacker", []string{"water"}, 100, 100)
+		defender := fixtures.Pokemon("defender", []string{"normal"}, 100, 100)
+		battleContext := testBattleContext(1, attacker, defender)
+		if c.setup != nil {
+			c.setup(attacker, defender, battleContext)
+		}
+		_, breakdown := DamageCalculator(attacker, defender, fixtures.Instance(fixtures.Move(c.move, "fire", "physical", c.power, 100)), battleContext.TypeChart, battleContext)
+		if breakdown.Power != c.expectedPower {
+			t.Errorf("%s: Got power %d expected %d", c.move, breakdown.Power, c.expectedPower)
+		}
+	}
+}
+func setAilment(battleContext *api.BattleContext, pokemon *api.Pokemon, ailment string) {
+	state := battleContext.PokemonStates[pokemon]
+	state.Ailment = &api.AilmentState{Name: ailment}
+	battleContext.PokemonStates[pokemon] = state
+}
+
+func TestDirectDamage(t *testing.T) {
+	cases := []struct {
+		move                string
+		moveType            string
+		defenderTypes       []string
+		attackerHp          int
+		expectedDamage      int
+		expectedEffect      float64
+	}{
+		{move: "sonic-boom", moveType: "normal", defenderTypes: []string{"rock"}, expectedDamage: 20, expectedEffect: 1},
+		{move: "dragon-rage", moveType: "dragon", defenderTypes: []string{"fire"}, expectedDamage: 40, expectedEffect: 1},
+		{move: "seismic-toss", moveType: "fighting", defenderTypes: []string{"normal"}, expectedDamage: 50, expectedEffect: 1},
+		{move: "night-shade", moveType: "ghost", defenderTypes: []string{"fire"}, expectedDamage: 50, expectedEffect: 1},
+		{move: "sonic-boom", moveType: "normal", defenderTypes: []string{"ghost"}, expectedDamage: 0, expectedEffect: 0},
+		{move: "super-fang", moveType: "normal", defenderTypes: []string{"fire"}, expectedDamage: 50, expectedEffect: 1},
+		{move: "endeavor", moveType: "normal", defenderTypes: []string{"fire"}, attackerHp: 10, expectedDamage: 90, expectedEffect: 1},
+	}
+	for _, c := range cases {
+		attacker := fixtures.Pokemon("attacker", []string{"water"}, 100, 100)
+		defender := fixtures.Pokemon("defender", c.defenderTypes, 100, 100)
+		if c.attackerHp > 0 {
+			attacker.CurrHp = c.attackerHp
+		}
+		battleContext := testBattleContext(1, attacker, defender)
+		moveOutcome := &MoveOutcome{NumHits: 1, Effectiveness: 1}
+		damageEngine(attacker, defender, fixtures.Instance(fixtures.Move(c.move, c.moveType, "physical", 0, 100)), battleContext, moveOutcome)
+		if moveOutcome.Damage != c.expectedDamage || moveOutcome.Effectiveness != c.expectedEffect {
+			t.Errorf("%s vs %v: Got %d damage (effectiveness %v) expected %d (%v)", c.move, c.defenderTypes, moveOutcome.Damage, moveOutcome.Effectiveness, c.expectedDamage, c.expectedEffect)
+		}
+	}
+}
+
+func TestExpectedDamage(t *testing.T) {
+	cases := []struct {
+		name                string
+		move                *api.MoveInstance
+		accuracy            int
+		hits                int
+		expected            float64
+	}{
+		{name: "average roll no critical", move: fixtures.Instance(fixtures.Move("flamethrower", "fire", "special", 80, 100)), expected: 102},
+		{name: "scaled by accuracy", move: fixtures.Instance(fixtures.Move("fire-blast", "fire", "special", 80, 100)), accuracy: 50, expected: 51},
+		{name: "every hit of a fixed multi-hit move", move: fixtures.Instance(fixtures.Move("double-kick", "fire", "physical", 80, 100)), hits: 2, expected: 204},
+		{name: "status moves deal nothing", move: fixtures.Instance(fixtures.Move("will-o-wisp", "fire", "status", 0, 100)), expected: 0},
+	}
+	for _, c := range cases {
+		attacker := fixtures.Pokemon("charizard", []string{"fire"}, 100, 100)
+		defender := fixtures.Pokemon("venusaur", []string{"grass"}, 100, 100)
+		battleContext := testBattleContext(1, attacker, defender)
+		if c.accuracy > 0 {
+			c.move.Detail.Accuracy = c.accuracy
+		}
+		c.move.Detail.Meta.MinHits, c.move.Detail.Meta.MaxHits = c.hits, c.hits
+		untouched := rand.New(rand.NewSource(1)).Int63()
+		if got := ExpectedDamage(attacker, defender, c.move, battleContext); got != c.expected {
+			t.Errorf("%s: Got %v expected %v", c.name, got, c.expected)
+		}
+		if battleContext.Rng.Int63() != untouched {
+			t.Errorf("%s: Got the battle rng advanced expected the estimate to leave it alone", c.name)
+		}
+	}
+}
+
+func TestSelfTargetingMovesIgnoreSemiInvulnerability(t *testing.T) {
+	attacker := fixtures.Pokemon("attacker", []string{"normal"}, 100, 100)
+	defender := fixtures.Pokemon("defender", []string{"flying"}, 100, 100)
+	battleContext := testBattleContext(1, attacker, defender)
+	fly := fixtures.Instance(fixtures.Move("fly", "flying", "physical", 90, 100)).Detail
+	defenderState := battleContext.PokemonStates[defender]
+	defenderState.SemiInvuln = &api.SemiInvulnState{Move: fly, Turn: 1}
+	battleContext.PokemonStates[defender] = defenderState
+
+	swordsDance := fixtures.Instance(fixtures.Move("swords-dance", "normal", "status", 0, 100))
+	swordsDance.Detail.Accuracy = 0
+	swordsDance.Detail.Target = api.TargetType{Name: "user"}
+	swordsDance.Detail.StatChange = []api.StatChange{{Change: 2, Stat: api.Stat{Name: "attack"}}}
+	outcome := HandleMoveExecution(attacker, defender, swordsDance, battleContext)
+	if outcome.Missed || outcome.UserStatChanges["attack"] != 2 {
+		t.Errorf("Got missed %v and changes %v expected swords-dance to raise attack by 2", outcome.Missed, outcome.UserStatChanges)
+	}
+	// a move aimed at the flying foe still misses
+	tackle := fixtures.Instance(fixtures.Move("tackle", "normal", "physical", 40, 100))
+	tackle.Detail.Target = api.TargetType{Name: "selected-pokemon"}
+	if outcome := HandleMoveExecution(attacker, defender, tackle, battleContext); !outcome.Missed {
+		t.Errorf("Got a hit expected tackle to miss the pokemon using fly")
+	}
+}
@@ -0,0 +1,63 @@
+package damageCalculator
+
+import (
+	"slices"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+)
+
+// average number of hits for a 2-5 hit move with handleMultiHit's 35/35/15/15 split
+const averageMultiHits = 3.1
+
+// HitChance is the chance (0-1) the move lands, worked out without rolling for it
+func HitChance(attacker, defender *api.Pokemon, move *api.MoveDetail, battleContext *api.BattleContext) float64 {
+	if (slices.Contains(attacker.Type, "poison") && move.Name == "toxic") || !TargetsOpponent(move) {
+		return 1
+	}
+	if semiInvulnData := battleContext.PokemonStates[defender].SemiInvuln; semiInvulnData != nil {
+		if slices.Contains(MovesDamagingSemiVulnerable[semiInvulnData.Move.Name], move.Name) {
+			return 1
+		}
+		return 0
+	}
+	accuracy, _ := moveAccuracy(attacker, move, defender, battleContext.Weather.Name)
+	return accuracy / 100
+}
+
+// ExpectedDamage estimates what the move does to the defender on average without touching the
+// battle's rng: an average non critical hit, times the average number of hits, times the chance
+// to land. Moves that lose a turn to charging or recharging count for half
+func ExpectedDamage(attacker, defender *api.Pokemon, moveInst *api.MoveInstance, battleContext *api.BattleContext) float64 {
+	move := moveInst.Detail
+	if move.DamageClass.Name == "status" {
+		return 0
+	}
+	var perHit float64
+	switch {
+	case FixedDamage[move.Name] || DirectDamageAttacks[move.Name]:
+		if TypeEffectiveness(battleContext.TypeChart, move.Type.Name, defender.Type) == 0 {
+			return 0
+		}
+		if move.Name == "psywave" {
+			// psywave averages out at the user's level
+			perHit = float64(attacker.Level)
+		} else {
+			perHit = float64(calcDirectDamage(attacker, defender, move, nil))
+		}
+	default:
+		damage, _ := hitDamage(attacker, defender, moveInst, battleContext.TypeChart, battleContext, averageRolls{})
+		perHit = float64(damage)
+	}
+	hits := 1.0
+	switch minHits, maxHits := move.Meta.MinHits, move.Meta.MaxHits; {
+	case minHits > 0 && minHits == maxHits:
+		hits = float64(minHits)
+	case maxHits > minHits:
+		hits = averageMultiHits
+	}
+	expected := perHit * hits * HitChance(attacker, defender, move, battleContext)
+	if IsChargeTurn(attacker, move, battleContext) || RechargingMoves[move.Name] {
+		expected /= 2
+	}
+	return expected
+}
@@ -0,0 +1,154 @@
+package damageCalculator
+
+import (
+	"slices"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+)
+
+// weather and terrain set by a move last 5 turns
+const fieldDuration = 5
+
+var WeatherByMove = map[string]string{
+	"sunny-day": "sun",
+	"rain-dance": "rain",
+	"sandstorm": "sandstorm",
+	"hail": "hail",
+	"snowscape": "snow",
+	"chilly-reception": "snow",
+}
+var TerrainByMove = map[string]string{
+	"electric-terrain": "electric",
+	"grassy-terrain": "grassy",
+	"psychic-terrain": "psychic",
+	"misty-terrain": "misty",
+}
+// the types that take no chip damage from each weather
+var WeatherImmuneTypes = map[string][]string{
+	"sandstorm": {"rock", "ground", "steel"},
+	"hail": {"ice"},
+}
+var MovesThatChargeInstantlyInSun = map[string]bool{
+	"solar-beam": true,
+	"solar-blade": true,
+}
+// terrains boost moves of their type used by a grounded pokemon
+var TerrainBoostedType = map[string]string{
+	"electric": "electric",
+	"grassy": "grass",
+	"psychic": "psychic",
+}
+var MovesWeakenedByGrassyTerrain = map[string]bool{
+	"earthquake": true,
+	"bulldoze": true,
+	"magnitude": true,
+}
+
+// IsGrounded is false for pokemon terrain can't reach - flying types and levitate users
+func IsGrounded(pokemon *api.Pokemon) bool {
+	return !slices.Contains(pokemon.Type, "flying") && pokemon.Ability != "levitate"
+}
+
+// setField starts the weather or terrain a move calls for. Calling the one already in effect fails
+func setField(move *api.MoveDetail, battleContext *api.BattleContext, moveOutcome *MoveOutcome) {
+	field, name := &battleContext.Weather, WeatherByMove[move.Name]
+	if name == "" {
+		field, name = &battleContext.Terrain, TerrainByMove[move.Name]
+	}
+	if name == "" {
+		return
+	}
+	if field.Name == name {
+		if move.DamageClass.Name == "status" {
+			moveOutcome.StatusFailed = true
+		}
+		return
+	}
+	*field = api.FieldState{Name: name, TurnsLeft: fieldDuration}
+	moveOutcome.FieldChange = name
+}
+
+// weatherModifier is sun and rain boosting or weakening fire and water moves, and the weakened
+// solar moves in any other weather
+func weatherModifier(move *api.MoveDetail, weather string) float64 {
+	switch {
+	case weather == "sun" && move.Type.Name == "fire", weather == "rain" && move.Type.Name == "water":
+		return 1.5
+	case weather == "sun" && move.Type.Name == "water", weather == "rain" && move.Type.Name == "fire":
+		return 0.5
+	case weather != "" && weather != "sun" && MovesThatChargeInstantlyInSun[move.Name]:
+		return 0.5
+	}
+	return 1
+}
+
+// terrainModifier is the boost a grounded attacker gets from its terrain plus misty terrain halving
+// dragon moves and grassy terrain halving earthquake against a grounded target
+func terrainModifier(attacker, defender *api.Pokemon, move *api.MoveDetail, terrain string) float64 {
+	modifier := 1.0
+	if boosted, ok := TerrainBoostedType[terrain]; ok && boosted == move.Type.Name && IsGrounded(attacker) {
+		modifier *= 1.3
+	}
+	if IsGrounded(defender) && ((terrain == "misty" && move.Type.Name == "dragon") || (terrain == "grassy" && MovesWeakenedByGrassyTerrain[move.Name])) {
+		modifier *= 0.5
+	}
+	return modifier
+}
+
+// weatherDefenseBoost is sandstorm raising rock types' special defense and snow raising ice types' defense
+func weatherDefenseBoost(defender *api.Pokemon, defenseStatName, weather string) float64 {
+	if weather == "sandstorm" && defenseStatName == "special-defense" && slices.Contains(defender.Type, "rock") {
+		return 1.5
+	}
+	if weather == "snow" && defenseStatName == "defense" && slices.Contains(defender.Type, "ice") {
+		return 1.5
+	}
+	return 1
+}
+
+// weatherAccuracy overrides a move's accuracy in weather: thunder and hurricane never miss in rain and
+// drop to 50 in sun, blizzard never misses in hail or snow. 0 means the move can't miss
+func weatherAccuracy(move *api.MoveDetail, weather string) int {
+	switch move.Name {
+	case "thunder", "hurricane":
+		if weather == "rain" {
+			return 0
+		}
+		if weather == "sun" {
+			return 50
+		}
+	case "blizzard":
+		if weather == "hail" || weather == "snow" {
+			return 0
+		}
+	}
+	return move.Accuracy
+}
+
+// TerrainBlocksAilment is electric terrain keeping grounded pokemon awake and misty terrain
+// protecting them from every status condition and confusion
+func TerrainBlocksAilment(pokemon *api.Pokemon, ailment, terrain string) bool {
+	if !IsGrounded(pokemon) {
+		return false
+	}
+	return (terrain == "electric" && ailment == "sleep") || terrain == "misty"
+}
+
+// TerrainBlocksPriority is psychic terrain stopping priority moves aimed at a grounded pokemon
+func TerrainBlocksPriority(defender *api.Pokemon, move *api.MoveDetail, terrain string) bool {
+	return terrain == "psychic" && move.Priority > 0 && TargetsOpponent(move) && IsGrounded(defender)
+}
+
+// WeatherChipDamage is the end of turn 1/16 max hp sandstorm and hail deal to anything not immune
+func WeatherChipDamage(pokemon *api.Pokemon, weather string) int {
+	immuneTypes, ok := WeatherImmuneTypes[weather]
+	if !ok {
+		return 0
+	}
+	for _, immuneType := range immuneTypes {
+		if slices.Contains(pokemon.Type, immuneType) {
+			return 0
+		}
+	}
+	return max(maxHp(pokemon) / 16, 1)
+}
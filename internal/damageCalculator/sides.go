@@ -0,0 +1,269 @@
+package damageCalculator
+
+import (
+	"slices"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+)
+
+// screens last 5 turns on the side that set them up
+const screenDuration = 5
+
+var ScreenByMove = map[string]string{
+	"reflect": "reflect",
+	"baddy-bad": "reflect",
+	"light-screen": "light-screen",
+	"glitzy-glow": "light-screen",
+	"aurora-veil": "aurora-veil",
+}
+var HazardByMove = map[string]string{
+	"spikes": "spikes",
+	"ceaseless-edge": "spikes",
+	"stealth-rock": "stealth-rock",
+	"stone-axe": "stealth-rock",
+	"toxic-spikes": "toxic-spikes",
+	"sticky-web": "sticky-web",
+}
+var Screens = []string{"reflect", "light-screen", "aurora-veil"}
+var Hazards = []string{"spikes", "toxic-spikes", "stealth-rock", "sticky-web"}
+
+// SideConditionChange is a screen or hazard a move set up or cleared away on one side of the field
+type SideConditionChange struct {
+	Side                      int
+	Condition                 string
+	Removed                   bool
+}
+
+func sideOf(pokemon *api.Pokemon, battleContext *api.BattleContext) int {
+	return battleContext.PokemonStates[pokemon].Side
+}
+
+// HasSideCondition reports whether the condition is up on the side
+func HasSideCondition(sides *api.SideConditions, condition string) bool {
+	switch condition {
+	case "reflect":
+		return sides.Reflect > 0
+	case "light-screen":
+		return sides.LightScreen > 0
+	case "aurora-veil":
+		return sides.AuroraVeil > 0
+	case "spikes":
+		return sides.Spikes > 0
+	case "toxic-spikes":
+		return sides.ToxicSpikes > 0
+	case "stealth-rock":
+		return sides.StealthRock
+	case "sticky-web":
+		return sides.StickyWeb
+	}
+	return false
+}
+
+// addSideCondition puts up a screen or lays another layer of a hazard, false when it is already at its limit
+func addSideCondition(sides *api.SideConditions, condition string) bool {
+	switch condition {
+	case "reflect", "light-screen", "aurora-veil":
+		if HasSideCondition(sides, condition) {
+			return false
+		}
+		switch condition {
+		case "reflect":
+			sides.Reflect = screenDuration
+		case "light-screen":
+			sides.LightScreen = screenDuration
+		default:
+			sides.AuroraVeil = screenDuration
+		}
+	case "spikes":
+		if sides.Spikes >= 3 {
+			return false
+		}
+		sides.Spikes++
+	case "toxic-spikes":
+		if sides.ToxicSpikes >= 2 {
+			return false
+		}
+		sides.ToxicSpikes++
+	case "stealth-rock":
+		if sides.StealthRock {
+			return false
+		}
+		sides.StealthRock = true
+	case "sticky-web":
+		if sides.StickyWeb {
+			return false
+		}
+		sides.StickyWeb = true
+	default:
+		return false
+	}
+	return true
+}
+
+// RemoveSideCondition clears a screen or every layer of a hazard, false when it wasn't there
+func RemoveSideCondition(sides *api.SideConditions, condition string) bool {
+	if !HasSideCondition(sides, condition) {
+		return false
+	}
+	switch condition {
+	case "reflect":
+		sides.Reflect = 0
+	case "light-screen":
+		sides.LightScreen = 0
+	case "aurora-veil":
+		sides.AuroraVeil = 0
+	case "spikes":
+		sides.Spikes = 0
+	case "toxic-spikes":
+		sides.ToxicSpikes = 0
+	case "stealth-rock":
+		sides.StealthRock = false
+	case "sticky-web":
+		sides.StickyWeb = false
+	}
+	return true
+}
+
+func clearSideConditions(battleContext *api.BattleContext, side int, conditions []string, moveOutcome *MoveOutcome) {
+	for _, condition := range conditions {
+		if RemoveSideCondition(&battleContext.Sides[side], condition) {
+			moveOutcome.SideChanges = append(moveOutcome.SideChanges, SideConditionChange{Side: side, Condition: condition, Removed: true})
+		}
+	}
+}
+
+// breakScreens is brick-break and co shattering the target's screens before they hit
+func breakScreens(attacker, defender *api.Pokemon, move *api.MoveDetail, battleContext *api.BattleContext, moveOutcome *MoveOutcome) {
+	if !ScreenRemovingMoves[move.Name] || move.DamageClass.Name == "status" {
+		return
+	}
+	if TypeEffectiveness(battleContext.TypeChart, move.Type.Name, defender.Type) == 0 {
+		return
+	}
+	clearSideConditions(battleContext, sideOf(defender, battleContext), Screens, moveOutcome)
+}
+
+// setSideConditions handles the moves that put up screens on the user's side, lay hazards on the
+// target's side or clear them away: rapid-spin and mortal-spin clean the user's side, tidy-up both
+// sides and defog clears the target's screens along with every hazard on the field
+func setSideConditions(attacker, defender *api.Pokemon, move *api.MoveDetail, battleContext *api.BattleContext, moveOutcome *MoveOutcome) {
+	isStatusMove := move.DamageClass.Name == "status"
+	if !isStatusMove && moveOutcome.Effectiveness == 0 {
+		return
+	}
+	userSide, targetSide := sideOf(attacker, battleContext), sideOf(defender, battleContext)
+	failed := false
+	if screen, ok := ScreenByMove[move.Name]; ok {
+		weather := battleContext.Weather.Name
+		if screen == "aurora-veil" && weather != "hail" && weather != "snow" {
+			failed = true
+		} else if addSideCondition(&battleContext.Sides[userSide], screen) {
+			moveOutcome.SideChanges = append(moveOutcome.SideChanges, SideConditionChange{Side: userSide, Condition: screen})
+		} else {
+			failed = true
+		}
+	}
+	if hazard, ok := HazardByMove[move.Name]; ok {
+		if addSideCondition(&battleContext.Sides[targetSide], hazard) {
+			moveOutcome.SideChanges = append(moveOutcome.SideChanges, SideConditionChange{Side: targetSide, Condition: hazard})
+		} else {
+			failed = true
+		}
+	}
+	if EntryHazardRemove[move.Name] {
+		clearSideConditions(battleContext, userSide, Hazards, moveOutcome)
+		if move.Name == "defog" || move.Name == "tidy-up" {
+			clearSideConditions(battleContext, targetSide, Hazards, moveOutcome)
+		}
+		if move.Name == "defog" {
+			clearSideConditions(battleContext, targetSide, Screens, moveOutcome)
+		}
+	}
+	if failed && isStatusMove {
+		moveOutcome.StatusFailed = true
+	}
+}
+
+// screenModifier halves damage through reflect (physical), light-screen (special) or aurora-veil
+// (both) on the defender's side. Critical hits go straight through screens
+func screenModifier(defender *api.Pokemon, move *api.MoveDetail, critical bool, battleContext *api.BattleContext) float64 {
+	if critical {
+		return 1
+	}
+	sides := &battleContext.Sides[sideOf(defender, battleContext)]
+	if sides.AuroraVeil > 0 || (move.DamageClass.Name == "physical" && sides.Reflect > 0) || (move.DamageClass.Name == "special" && sides.LightScreen > 0) {
+		return 0.5
+	}
+	return 1
+}
+
+// TickScreens counts the side's screens down at the end of the turn and returns the ones that wore off
+func TickScreens(sides *api.SideConditions) []string {
+	ended := []string{}
+	for _, screen := range []struct {
+		name  string
+		turns *int
+	}{{"reflect", &sides.Reflect}, {"light-screen", &sides.LightScreen}, {"aurora-veil", &sides.AuroraVeil}} {
+		if *screen.turns == 0 {
+			continue
+		}
+		*screen.turns--
+		if *screen.turns == 0 {
+			ended = append(ended, screen.name)
+		}
+	}
+	return ended
+}
+
+// EntryHazardOutcome is what the hazards on a side do to a pokemon switching in
+type EntryHazardOutcome struct {
+	SpikesDamage              int
+	StealthRockDamage         int
+	Ailment                   string // poison or bad-poison from toxic-spikes
+	AbsorbedToxicSpikes       bool // a grounded poison type cleans toxic-spikes up
+	SpeedDrop                 bool // sticky-web lowered its speed
+}
+
+// ApplyEntryHazards runs the hazards on the pokemon's side against it as it switches in: spikes deal
+// 1/8, 1/6 or 1/4 of max hp by layers, stealth-rock 1/8 scaled by the rock type matchup, toxic-spikes
+// poison (two layers badly poison) and sticky-web lowers speed a stage. Only stealth-rock reaches
+// pokemon that aren't grounded. Status and stat changes are applied here, the hp loss is left to the caller
+func ApplyEntryHazards(pokemon *api.Pokemon, battleContext *api.BattleContext) EntryHazardOutcome {
+	outcome := EntryHazardOutcome{}
+	side := sideOf(pokemon, battleContext)
+	sides := &battleContext.Sides[side]
+	hp := maxHp(pokemon)
+
+	if sides.StealthRock {
+		effectiveness := TypeEffectiveness(battleContext.TypeChart, "rock", pokemon.Type)
+		outcome.StealthRockDamage = max(int(float64(hp) * effectiveness / 8), 1)
+	}
+	if IsGrounded(pokemon) {
+		switch sides.Spikes {
+		case 1:
+			outcome.SpikesDamage = max(hp / 8, 1)
+		case 2:
+			outcome.SpikesDamage = max(hp / 6, 1)
+		case 3:
+			outcome.SpikesDamage = max(hp / 4, 1)
+		}
+		if sides.ToxicSpikes > 0 {
+			state := battleContext.PokemonStates[pokemon]
+			if slices.Contains(pokemon.Type, "poison") {
+				sides.ToxicSpikes = 0
+				outcome.AbsorbedToxicSpikes = true
+			} else if state.Ailment == nil && !slices.Contains(pokemon.Type, "steel") && !TerrainBlocksAilment(pokemon, "poison", battleContext.Terrain.Name) {
+				outcome.Ailment = "poison"
+				if sides.ToxicSpikes >= 2 {
+					outcome.Ailment = "bad-poison"
+				}
+				state.Ailment = &api.AilmentState{Name: outcome.Ailment}
+				battleContext.PokemonStates[pokemon] = state
+			}
+		}
+		if sides.StickyWeb {
+			outcome.SpeedDrop = ApplyStatStage(pokemon, battleContext, "speed", -1) != 0
+		}
+	}
+	return outcome
+}
@@ -0,0 +1,219 @@
+package evolution
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/statCalculator"
+)
+
+// the trigger names the api uses, friendship evolutions are level-up with a min happiness
+const (
+	TriggerLevelUp   = "level-up"
+	TriggerUseItem   = "use-item"
+	TriggerTrade     = "trade"
+)
+
+const MaxFriendship = 255
+
+// Attempt is the way the user is trying to evolve a pokemon
+type Attempt struct {
+	Trigger          string
+	Item             string // the item used, or held while being traded
+	TimeOfDay        string // day or night, see TimeOfDay
+}
+
+// TimeOfDay is "day" from 6 in the morning until 6 in the evening and "night" otherwise
+func TimeOfDay(now time.Time) string {
+	if hour := now.Hour(); hour >= 6 && hour < 18 {
+		return "day"
+	}
+	return "night"
+}
+
+// Next lists the links the species can evolve into, nil when it is fully evolved or not in the chain
+func Next(chain api.UnmarshaledEvolutionChain, species string) []api.ChainLink {
+	if link := findLink(&chain.Chain, species); link != nil {
+		return link.EvolvesTo
+	}
+	return nil
+}
+func findLink(link *api.ChainLink, species string) *api.ChainLink {
+	if link.Species.Name == species {
+		return link
+	}
+	for i := range link.EvolvesTo {
+		if found := findLink(&link.EvolvesTo[i], species); found != nil {
+			return found
+		}
+	}
+	return nil
+}
+
+// Find is the first species the pokemon can evolve into with the attempt
+func Find(chain api.UnmarshaledEvolutionChain, pokemon *api.Pokemon, attempt Attempt) (string, bool) {
+	for _, link := range Next(chain, pokemon.Species) {
+		for _, detail := range link.EvolutionDetails {
+			if Meets(pokemon, detail, attempt) {
+				return link.Species.Name, true
+			}
+		}
+	}
+	return "", false
+}
+
+// Meets reports whether the pokemon evolves with the attempt. Conditions the pokedex has nothing to
+// check against (gender, location, weather, party, beauty and affection) are never met
+func Meets(pokemon *api.Pokemon, detail api.EvolutionDetail, attempt Attempt) bool {
+	if detail.Trigger.Name != attempt.Trigger {
+		return false
+	}
+	if detail.Gender != nil || detail.Location != nil || detail.PartySpecies != nil || detail.PartyType != nil ||
+		detail.TradeSpecies != nil || detail.NeedsOverworldRain || detail.TurnUpsideDown || detail.MinBeauty > 0 || detail.MinAffection > 0 {
+		return false
+	}
+	if pokemon.Level < detail.MinLevel || pokemon.Friendship < detail.MinHappiness {
+		return false
+	}
+	if detail.TimeOfDay != "" && detail.TimeOfDay != attempt.TimeOfDay {
+		return false
+	}
+	// the item is the one used for use-item evolutions and the held one for everything else
+	switch {
+	case detail.Item != nil && detail.Item.Name != attempt.Item:
+		return false
+	case detail.HeldItem != nil && detail.HeldItem.Name != attempt.Item:
+		return false
+	case detail.Item == nil && detail.HeldItem == nil && attempt.Item != "":
+		return false
+	}
+	if detail.KnownMove != nil && !knowsMove(pokemon, func(move *api.MoveDetail) bool { return move.Name == detail.KnownMove.Name }) {
+		return false
+	}
+	if detail.KnownMoveType != nil && !knowsMove(pokemon, func(move *api.MoveDetail) bool { return move.Type.Name == detail.KnownMoveType.Name }) {
+		return false
+	}
+	if detail.RelativePhysicalStats != nil {
+		difference := pokemon.Stats["attack"].StatValue - pokemon.Stats["defense"].StatValue
+		if sign(difference) != *detail.RelativePhysicalStats {
+			return false
+		}
+	}
+	return true
+}
+func knowsMove(pokemon *api.Pokemon, matches func(*api.MoveDetail) bool) bool {
+	for _, moveInst := range pokemon.Moves {
+		if moveInst != nil && matches(moveInst.Detail) {
+			return true
+		}
+	}
+	return false
+}
+func sign(n int) int {
+	switch {
+	case n > 0:
+		return 1
+	case n < 0:
+		return -1
+	}
+	return 0
+}
+
+// Describe spells out what an evolution needs, like "level up at level 16" or "use a thunder-stone"
+func Describe(detail api.EvolutionDetail) string {
+	parts := []string{}
+	switch detail.Trigger.Name {
+	case TriggerLevelUp:
+		if detail.MinLevel > 0 {
+			parts = append(parts, fmt.Sprintf("level up at level %d", detail.MinLevel))
+		} else {
+			parts = append(parts, "level up")
+		}
+	case TriggerUseItem:
+		if detail.Item != nil {
+			parts = append(parts, "use a " + detail.Item.Name)
+		}
+	case TriggerTrade:
+		parts = append(parts, "trade")
+	default:
+		parts = append(parts, detail.Trigger.Name)
+	}
+	if detail.HeldItem != nil {
+		parts = append(parts, "holding a " + detail.HeldItem.Name)
+	}
+	if detail.MinHappiness > 0 {
+		parts = append(parts, fmt.Sprintf("with friendship %d", detail.MinHappiness))
+	}
+	if detail.KnownMove != nil {
+		parts = append(parts, "knowing " + detail.KnownMove.Name)
+	}
+	if detail.KnownMoveType != nil {
+		parts = append(parts, "knowing a " + detail.KnownMoveType.Name + " move")
+	}
+	if detail.TimeOfDay != "" {
+		parts = append(parts, "at " + detail.TimeOfDay)
+	}
+	if detail.TradeSpecies != nil {
+		parts = append(parts, "for a " + detail.TradeSpecies.Name)
+	}
+	if detail.Location != nil || detail.Gender != nil || detail.PartySpecies != nil || detail.PartyType != nil ||
+		detail.NeedsOverworldRain || detail.TurnUpsideDown || detail.MinBeauty > 0 || detail.MinAffection > 0 {
+		parts = append(parts, "(with conditions the pokedex can't meet)")
+	}
+	return strings.Join(parts, " ")
+}
+
+// Evolve turns the pokemon into the species described by pokemonData. Level, experience, ivs, evs,
+// nature, moves, friendship and nickname carry over and the stats are worked out again from the new
+// base stats. The ability stays if the new species can have it, otherwise it takes the first one
+func Evolve(pokemon *api.Pokemon, species string, pokemonData api.UnmarshaledPokemonInfo) error {
+	baseStats := make(map[string]api.StatData, len(pokemonData.BaseStats))
+	for _, statEntry := range pokemonData.BaseStats {
+		baseStats[strings.ToLower(statEntry.Stat.Name)] = statEntry
+	}
+	for _, stat := range statCalculator.StatNames {
+		if _, ok := baseStats[stat]; !ok {
+			return fmt.Errorf("evolving into %s: missing base %s", species, stat)
+		}
+	}
+	for _, stat := range statCalculator.StatNames {
+		bundle := pokemon.Stats[stat]
+		bundle.BaseStat = baseStats[stat].BaseStat
+		bundle.EffortValue = baseStats[stat].Effort
+		pokemon.Stats[stat] = bundle
+	}
+	typeNames := make([]string, len(pokemonData.Type))
+	for i, t := range pokemonData.Type {
+		typeNames[i] = t.Type.Name
+	}
+	abilities := make([]string, len(pokemonData.Abilities))
+	for i, abilityData := range pokemonData.Abilities {
+		abilities[i] = abilityData.Ability.Name
+	}
+	if !slices.Contains(abilities, pokemon.Ability) && len(abilities) > 0 {
+		pokemon.Ability = abilities[0]
+	}
+	pokemon.Species = species
+	pokemon.Type = typeNames
+	pokemon.Weight = pokemonData.Weight
+	pokemon.BaseExp = pokemonData.BaseExp
+	if pokemonData.GrowthRate != "" {
+		pokemon.GrowthRate = pokemonData.GrowthRate
+	}
+	return statCalculator.RecalculateStats(pokemon)
+}
+
+// LevelUpFriendship raises friendship for a level gained: 5 below 100, 3 below 200 and 2 after that
+func LevelUpFriendship(pokemon *api.Pokemon) {
+	gain := 2
+	switch {
+	case pokemon.Friendship < 100:
+		gain = 5
+	case pokemon.Friendship < 200:
+		gain = 3
+	}
+	pokemon.Friendship = min(pokemon.Friendship + gain, MaxFriendship)
+}
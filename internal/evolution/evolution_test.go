@@ -0,0 +1,107 @@
+package evolution
+
+import (
+	"testing"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+)
+
+func named(name string) *api.NamedResource {
+	return &api.NamedResource{Name: name}
+}
+func link(species string, details []api.EvolutionDetail, evolvesTo ...api.ChainLink) api.ChainLink {
+	return api.ChainLink{Species: api.NamedResource{Name: species}, EvolutionDetails: details, EvolvesTo: evolvesTo}
+}
+func trigger(name string) api.NamedResource {
+	return api.NamedResource{Name: name}
+}
+
+func TestFind(t *testing.T) {
+	charmanderChain := api.UnmarshaledEvolutionChain{Chain: link("charmander", nil,
+		link("charmeleon", []api.EvolutionDetail{{Trigger: trigger(TriggerLevelUp), MinLevel: 16}},
+			link("charizard", []api.EvolutionDetail{{Trigger: trigger(TriggerLevelUp), MinLevel: 36}})))}
+	eeveeChain := api.UnmarshaledEvolutionChain{Chain: link("eevee", nil,
+		link("jolteon", []api.EvolutionDetail{{Trigger: trigger(TriggerUseItem), Item: named("thunder-stone")}}),
+		link("espeon", []api.EvolutionDetail{{Trigger: trigger(TriggerLevelUp), MinHappiness: 160, TimeOfDay: "day"}}),
+		link("umbreon", []api.EvolutionDetail{{Trigger: trigger(TriggerLevelUp), MinHappiness: 160, TimeOfDay: "night"}}),
+		link("sylveon", []api.EvolutionDetail{{Trigger: trigger(TriggerLevelUp), MinAffection: 2, KnownMoveType: named("fairy")}}))}
+	onixChain := api.UnmarshaledEvolutionChain{Chain: link("onix", nil,
+		link("steelix", []api.EvolutionDetail{{Trigger: trigger(TriggerTrade), HeldItem: named("metal-coat")}}))}
+
+	cases := []struct {
+		name        string
+		chain       api.UnmarshaledEvolutionChain
+		pokemon     *api.Pokemon
+		attempt     Attempt
+		expected    string // empty for no evolution
+	}{
+		{name: "level reached", chain: charmanderChain, pokemon: &api.Pokemon{Species: "charmander", Level: 16}, attempt: Attempt{Trigger: TriggerLevelUp}, expected: "charmeleon"},
+		{name: "level too low", chain: charmanderChain, pokemon: &api.Pokemon{Species: "charmander", Level: 15}, attempt: Attempt{Trigger: TriggerLevelUp}},
+		{name: "middle of the chain", chain: charmanderChain, pokemon: &api.Pokemon{Species: "charmeleon", Level: 40}, attempt: Attempt{Trigger: TriggerLevelUp}, expected: "charizard"},
+		{name: "fully evolved", chain: charmanderChain, pokemon: &api.Pokemon{Species: "charizard", Level: 100}, attempt: Attempt{Trigger: TriggerLevelUp}},
+		{name: "stone", chain: eeveeChain, pokemon: &api.Pokemon{Species: "eevee", Level: 5}, attempt: Attempt{Trigger: TriggerUseItem, Item: "thunder-stone"}, expected: "jolteon"},
+		{name: "wrong stone", chain: eeveeChain, pokemon: &api.Pokemon{Species: "eevee", Level: 5}, attempt: Attempt{Trigger: TriggerUseItem, Item: "fire-stone"}},
+		{name: "friendship by day", chain: eeveeChain, pokemon: &api.Pokemon{Species: "eevee", Level: 5, Friendship: 160}, attempt: Attempt{Trigger: TriggerLevelUp, TimeOfDay: "day"}, expected: "espeon"},
+		{name: "friendship by night", chain: eeveeChain, pokemon: &api.Pokemon{Species: "eevee", Level: 5, Friendship: 200}, attempt: Attempt{Trigger: TriggerLevelUp, TimeOfDay: "night"}, expected: "umbreon"},
+		{name: "friendship too low", chain: eeveeChain, pokemon: &api.Pokemon{Species: "eevee", Level: 5, Friendship: 70}, attempt: Attempt{Trigger: TriggerLevelUp, TimeOfDay: "day"}},
+		{name: "trade holding the item", chain: onixChain, pokemon: &api.Pokemon{Species: "onix", Level: 20}, attempt: Attempt{Trigger: TriggerTrade, Item: "metal-coat"}, expected: "steelix"},
+		{name: "trade without the item", chain: onixChain, pokemon: &api.Pokemon{Species: "onix", Level: 20}, attempt: Attempt{Trigger: TriggerTrade}},
+	}
+	for _, c := range cases {
+		actual, ok := Find(c.chain, c.pokemon, c.attempt)
+		if ok != (c.expected != "") || actual != c.expected {
+			t.Errorf("%s: Got %q (%v) expected %q", c.name, actual, ok, c.expected)
+		}
+	}
+}
+
+func TestEvolve(t *testing.T) {
+	tackle := &api.MoveInstance{RemainingPP: 20, Detail: &api.MoveDetail{Name: "tackle", PP: 35}}
+	stats := make(map[string]api.BundleStats)
+	for _, stat := range []string{"hp", "attack", "defense", "special-attack", "special-defense", "speed"} {
+		stats[stat] = api.BundleStats{BaseStat: 50, IVValue: 31, EVValue: 4, StatValue: 1}
+	}
+	pokemon := &api.Pokemon{Nickname: "rocky", Species: "geodude", Level: 25, Nature: "hardy", Ability: "sturdy", Moves: [4]*api.MoveInstance{tackle}, Stats: stats, CurrHp: 1}
+	gravelerData := api.UnmarshaledPokemonInfo{
+		Abilities: []api.AbilityData{{Ability: api.Ability{Name: "rock-head"}}, {Ability: api.Ability{Name: "sturdy"}}},
+		Type: []api.TypeData{{Type: api.Type{Name: "rock"}}, {Type: api.Type{Name: "ground"}}},
+		BaseExp: 137,
+	}
+	for _, stat := range []string{"hp", "attack", "defense", "special-attack", "special-defense", "speed"} {
+		gravelerData.BaseStats = append(gravelerData.BaseStats, api.StatData{BaseStat: 55, Effort: 1, Stat: api.Stat{Name: stat}})
+	}
+	if err := Evolve(pokemon, "graveler", gravelerData); err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	hp, attack := pokemon.Stats["hp"], pokemon.Stats["attack"]
+	// hp (2*55 + 31 + 1) * 25 / 100 + 25 + 10 = 70, attack (2*55 + 31 + 1) * 25 / 100 + 5 = 40
+	if hp.StatValue != 70 || attack.StatValue != 40 || hp.IVValue != 31 || attack.EVValue != 4 || attack.EffortValue != 1 {
+		t.Errorf("Got hp %+v attack %+v expected recalculated stats with the ivs and evs kept", hp, attack)
+	}
+	if pokemon.Species != "graveler" || pokemon.Nickname != "rocky" || pokemon.Ability != "sturdy" || pokemon.Moves[0] != tackle || len(pokemon.Type) != 2 || pokemon.BaseExp != 137 {
+		t.Errorf("Got %+v expected a graveler keeping its nickname, ability and moves", pokemon)
+	}
+	// current hp moves with max hp, from 1 of 1 to 70 of 70
+	if pokemon.CurrHp != 70 {
+		t.Errorf("Got %d hp expected 70", pokemon.CurrHp)
+	}
+}
+
+func TestLevelUpFriendship(t *testing.T) {
+	cases := []struct {
+		friendship  int
+		expected    int
+	}{
+		{friendship: 70, expected: 75},
+		{friendship: 150, expected: 153},
+		{friendship: 220, expected: 222},
+		{friendship: 254, expected: MaxFriendship},
+	}
+	for _, c := range cases {
+		pokemon := &api.Pokemon{Friendship: c.friendship}
+		LevelUpFriendship(pokemon)
+		if pokemon.Friendship != c.expected {
+			t.Errorf("Got %d expected %d", pokemon.Friendship, c.expected)
+		}
+	}
+}
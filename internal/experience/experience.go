@@ -0,0 +1,96 @@
+package experience
+
+import (
+	"github.com/rashadat1/goPokedex/internal/api"
+)
+
+const MaxLevel = 100
+
+// the growth rate names the api uses for the six curves
+const (
+	Fast             = "fast"
+	Medium           = "medium" // medium fast
+	MediumSlow       = "medium-slow"
+	Slow             = "slow"
+	Erratic          = "slow-then-very-fast"
+	Fluctuating      = "fast-then-very-slow"
+)
+
+// ForLevel is the total experience a pokemon on the growth rate needs to reach the level. Unknown
+// growth rates follow the medium curve
+func ForLevel(growthRate string, level int) int {
+	n := min(max(level, 1), MaxLevel)
+	if n == 1 {
+		return 0
+	}
+	cube := n * n * n
+	switch growthRate {
+	case Fast:
+		return 4 * cube / 5
+	case MediumSlow:
+		return 6 * cube / 5 - 15 * n * n + 100 * n - 140
+	case Slow:
+		return 5 * cube / 4
+	case Erratic:
+		switch {
+		case n < 50:
+			return cube * (100 - n) / 50
+		case n < 68:
+			return cube * (150 - n) / 100
+		case n < 98:
+			return cube * ((1911 - 10 * n) / 3) / 500
+		}
+		return cube * (160 - n) / 100
+	case Fluctuating:
+		switch {
+		case n < 15:
+			return cube * ((n + 1) / 3 + 24) / 50
+		case n < 36:
+			return cube * (n + 14) / 50
+		}
+		return cube * (n / 2 + 32) / 50
+	}
+	return cube
+}
+
+// LevelFor is the level a pokemon with that much experience has reached
+func LevelFor(growthRate string, experience int) int {
+	level := 1
+	for level < MaxLevel && experience >= ForLevel(growthRate, level + 1) {
+		level++
+	}
+	return level
+}
+
+// Yield is the experience for defeating the pokemon, shared between everyone who fought it
+func Yield(defeated *api.Pokemon, participants int) int {
+	if participants < 1 {
+		return 0
+	}
+	return max(defeated.BaseExp * defeated.Level / 7 / participants, 1)
+}
+
+// Gain adds experience to the pokemon and returns every level it reached on the way, in order.
+// A pokemon with less experience than its level needs (saved before experience was tracked) is
+// first brought up to the start of its level
+func Gain(pokemon *api.Pokemon, amount int) []int {
+	if pokemon.Level >= MaxLevel {
+		return nil
+	}
+	pokemon.Experience = max(pokemon.Experience, ForLevel(pokemon.GrowthRate, pokemon.Level))
+	pokemon.Experience = min(pokemon.Experience + amount, ForLevel(pokemon.GrowthRate, MaxLevel))
+	reached := []int{}
+	for newLevel := LevelFor(pokemon.GrowthRate, pokemon.Experience); pokemon.Level < newLevel; {
+		pokemon.Level++
+		reached = append(reached, pokemon.Level)
+	}
+	return reached
+}
+
+// ToNextLevel is how much more experience the pokemon needs for its next level, 0 at level 100
+func ToNextLevel(pokemon *api.Pokemon) int {
+	if pokemon.Level >= MaxLevel {
+		return 0
+	}
+	return max(ForLevel(pokemon.GrowthRate, pokemon.Level + 1) - pokemon.Experience, 0)
+}
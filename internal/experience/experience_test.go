@@ -0,0 +1,47 @@
+package experience
+
+import (
+	"testing"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+)
+
+func TestForLevel(t *testing.T) {
+	cases := []struct {
+		growthRate  string
+		level       int
+		expected    int
+	}{
+		{growthRate: Fast, level: 100, expected: 800000},
+		{growthRate: Medium, level: 100, expected: 1000000},
+		{growthRate: MediumSlow, level: 100, expected: 1059860},
+		{growthRate: MediumSlow, level: 2, expected: 9},
+		{growthRate: Slow, level: 100, expected: 1250000},
+		{growthRate: Erratic, level: 50, expected: 125000},
+		{growthRate: Erratic, level: 100, expected: 600000},
+		{growthRate: Fluctuating, level: 15, expected: 1957},
+		{growthRate: Fluctuating, level: 100, expected: 1640000},
+		{growthRate: Slow, level: 1, expected: 0},
+	}
+	for _, c := range cases {
+		if actual := ForLevel(c.growthRate, c.level); actual != c.expected {
+			t.Errorf("%s level %d: Got %d expected %d", c.growthRate, c.level, actual, c.expected)
+		}
+	}
+}
+
+func TestGain(t *testing.T) {
+	// level 5 needs 125 on the medium curve, 6 needs 216 and 7 needs 343
+	pokemon := &api.Pokemon{Level: 5, GrowthRate: Medium}
+	if reached := Gain(pokemon, 250); len(reached) != 2 || reached[0] != 6 || reached[1] != 7 || pokemon.Experience != 375 {
+		t.Errorf("Got levels %v and %d experience expected [6 7] and 375", reached, pokemon.Experience)
+	}
+	if next := ToNextLevel(pokemon); next != 512 - 375 {
+		t.Errorf("Got %d to the next level expected %d", next, 512 - 375)
+	}
+	pokemon = &api.Pokemon{Level: 99, GrowthRate: Medium, Experience: ForLevel(Medium, 99)}
+	Gain(pokemon, 10000000)
+	if pokemon.Level != MaxLevel || pokemon.Experience != ForLevel(Medium, MaxLevel) {
+		t.Errorf("Got level %d with %d experience expected the level 100 cap", pokemon.Level, pokemon.Experience)
+	}
+}
@@ -0,0 +1,61 @@
+package fixtures
+
+import (
+	"github.com/rashadat1/goPokedex/internal/api"
+)
+
+// fixtures builds the pokemon and moves the package tests battle with. It only imports api so the
+// tests of any package, battle and damageCalculator included, can use it without an import cycle
+
+var statNames = []string{"hp", "attack", "defense", "special-attack", "special-defense", "speed"}
+
+// Pokemon is a level 50 pokemon at full hp with 100 in every stat but hp and speed, knowing the
+// moves in order (a nil move leaves its slot empty)
+func Pokemon(species string, types []string, hp, speed int, moves ...*api.MoveDetail) *api.Pokemon {
+	stats := make(map[string]api.BundleStats)
+	for _, stat := range statNames {
+		stats[stat] = api.BundleStats{StatValue: 100}
+	}
+	stats["hp"] = api.BundleStats{StatValue: hp}
+	stats["speed"] = api.BundleStats{StatValue: speed}
+	pokemon := &api.Pokemon{
+		Species: species,
+		Level: 50,
+		CurrHp: hp,
+		Type: types,
+		Stats: stats,
+	}
+	for i, move := range moves {
+		if move != nil {
+			pokemon.Moves[i] = Instance(move)
+		}
+	}
+	return pokemon
+}
+
+// Move is a move with 35 pp. An accuracy of 0 never misses, so it doesn't draw from the battle's rng
+func Move(name, moveType, damageClass string, power, accuracy int) *api.MoveDetail {
+	return &api.MoveDetail{
+		Name: name,
+		Power: power,
+		PP: 35,
+		Accuracy: accuracy,
+		Type: api.Type{Name: moveType},
+		DamageClass: api.DamageClass{Name: damageClass},
+	}
+}
+
+// Instance is the move as a pokemon knows it, with full pp
+func Instance(move *api.MoveDetail) *api.MoveInstance {
+	return &api.MoveInstance{RemainingPP: move.PP, Detail: move}
+}
+
+// Trained is a level 50 pokemon of the nature with every base stat at baseStat and perfect ivs, for
+// working stats out from scratch
+func Trained(nature string, baseStat int) *api.Pokemon {
+	stats := make(map[string]api.BundleStats)
+	for _, stat := range statNames {
+		stats[stat] = api.BundleStats{IVValue: 31, BaseStat: baseStat}
+	}
+	return &api.Pokemon{Level: 50, Nature: nature, Stats: stats}
+}
@@ -0,0 +1,87 @@
+package natures
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	raisedMultiplier  = 1.1
+	loweredMultiplier = 0.9
+)
+
+// Nature raises one stat by 10% and lowers another by 10%. The five natures that would raise and
+// lower the same stat are neutral and leave Raised and Lowered empty
+type Nature struct {
+	Name           string
+	Raised         string
+	Lowered        string
+}
+
+// All lists the 25 natures in the games' index order
+var All = []Nature{
+	{Name: "Hardy"},
+	{Name: "Lonely", Raised: "attack", Lowered: "defense"},
+	{Name: "Brave", Raised: "attack", Lowered: "speed"},
+	{Name: "Adamant", Raised: "attack", Lowered: "special-attack"},
+	{Name: "Naughty", Raised: "attack", Lowered: "special-defense"},
+	{Name: "Bold", Raised: "defense", Lowered: "attack"},
+	{Name: "Docile"},
+	{Name: "Relaxed", Raised: "defense", Lowered: "speed"},
+	{Name: "Impish", Raised: "defense", Lowered: "special-attack"},
+	{Name: "Lax", Raised: "defense", Lowered: "special-defense"},
+	{Name: "Timid", Raised: "speed", Lowered: "attack"},
+	{Name: "Hasty", Raised: "speed", Lowered: "defense"},
+	{Name: "Serious"},
+	{Name: "Jolly", Raised: "speed", Lowered: "special-attack"},
+	{Name: "Naive", Raised: "speed", Lowered: "special-defense"},
+	{Name: "Modest", Raised: "special-attack", Lowered: "attack"},
+	{Name: "Mild", Raised: "special-attack", Lowered: "defense"},
+	{Name: "Quiet", Raised: "special-attack", Lowered: "speed"},
+	{Name: "Bashful"},
+	{Name: "Rash", Raised: "special-attack", Lowered: "special-defense"},
+	{Name: "Calm", Raised: "special-defense", Lowered: "attack"},
+	{Name: "Gentle", Raised: "special-defense", Lowered: "defense"},
+	{Name: "Sassy", Raised: "special-defense", Lowered: "speed"},
+	{Name: "Careful", Raised: "special-defense", Lowered: "special-attack"},
+	{Name: "Quirky"},
+}
+
+// Get looks a nature up by name, ignoring case
+func Get(name string) (Nature, bool) {
+	for _, nature := range All {
+		if strings.EqualFold(nature.Name, name) {
+			return nature, true
+		}
+	}
+	return Nature{}, false
+}
+
+func (n Nature) Neutral() bool {
+	return n.Raised == ""
+}
+
+// Multiplier is what the nature does to the stat: 1.1 raised, 0.9 lowered and 1 otherwise. Hp is
+// never affected and neither is anything with an unknown nature
+func Multiplier(name, stat string) float64 {
+	nature, ok := Get(name)
+	if !ok || nature.Neutral() {
+		return 1
+	}
+	switch stat {
+	case nature.Raised:
+		return raisedMultiplier
+	case nature.Lowered:
+		return loweredMultiplier
+	}
+	return 1
+}
+
+// Describe is the nature's effect for display, e.g. "+attack -special-attack" or "neutral"
+func Describe(name string) string {
+	nature, ok := Get(name)
+	if !ok || nature.Neutral() {
+		return "neutral"
+	}
+	return fmt.Sprintf("+%s -%s", nature.Raised, nature.Lowered)
+}
@@ -0,0 +1,46 @@
+package natures
+
+import "testing"
+
+func TestMultiplier(t *testing.T) {
+	cases := []struct {
+		nature      string
+		stat        string
+		expected    float64
+	}{
+		{nature: "Adamant", stat: "attack", expected: 1.1},
+		{nature: "Adamant", stat: "special-attack", expected: 0.9},
+		{nature: "Adamant", stat: "speed", expected: 1},
+		{nature: "modest", stat: "special-attack", expected: 1.1},
+		{nature: "Modest", stat: "attack", expected: 0.9},
+		{nature: "Timid", stat: "hp", expected: 1},
+		{nature: "Hardy", stat: "attack", expected: 1},
+		{nature: "Unknown", stat: "attack", expected: 1},
+	}
+	for _, c := range cases {
+		if actual := Multiplier(c.nature, c.stat); actual != c.expected {
+			t.Errorf("%s %s: Got %v expected %v", c.nature, c.stat, actual, c.expected)
+		}
+	}
+}
+
+func TestEveryStatRaisedAndLoweredOnce(t *testing.T) {
+	// each non-hp stat is raised by four natures and lowered by four, one of each pairing
+	raised, lowered, pairs := map[string]int{}, map[string]int{}, map[string]bool{}
+	for _, nature := range All {
+		if nature.Neutral() {
+			continue
+		}
+		raised[nature.Raised]++
+		lowered[nature.Lowered]++
+		pairs[nature.Raised + "/" + nature.Lowered] = true
+	}
+	for _, stat := range []string{"attack", "defense", "special-attack", "special-defense", "speed"} {
+		if raised[stat] != 4 || lowered[stat] != 4 {
+			t.Errorf("%s: Got raised %d lowered %d expected 4 and 4", stat, raised[stat], lowered[stat])
+		}
+	}
+	if len(All) != 25 || len(pairs) != 20 {
+		t.Errorf("Got %d natures with %d pairings expected 25 with 20", len(All), len(pairs))
+	}
+}
@@ -0,0 +1,162 @@
+package pokeapi
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/pokecache"
+)
+
+const DefaultBaseUrl = "https://pokeapi.co/api/v2/"
+
+// returned (wrapped) whenever the api answers with a 404 so callers can tell
+// a misspelled pokemon or move apart from a network failure
+var ErrNotFound = errors.New("resource not found")
+
+type Client struct {
+	baseUrl        string
+	httpClient     *http.Client
+	cache          *pokecache.Cache
+}
+
+func NewClient(cache *pokecache.Cache) *Client {
+	return &Client{
+		baseUrl: DefaultBaseUrl,
+		httpClient: &http.Client{Timeout: 10 * time.Second},
+		cache: cache,
+	}
+}
+
+// SetBaseUrl points the client at a different server - tests use this with an httptest server
+func (c *Client) SetBaseUrl(baseUrl string) {
+	if !strings.HasSuffix(baseUrl, "/") {
+		baseUrl += "/"
+	}
+	c.baseUrl = baseUrl
+}
+func (c *Client) BaseUrl() string {
+	return c.baseUrl
+}
+func (c *Client) FirstLocationAreasPage() string {
+	return c.baseUrl + "location-area?offset=0&limit=20"
+}
+
+// LocationAreas takes a full page url (the Next / Previous links returned by the api)
+func (c *Client) LocationAreas(pageUrl string) (api.UnmarshaledLocationAreas, error) {
+	if pageUrl == "" {
+		pageUrl = c.FirstLocationAreasPage()
+	}
+	locationAreas := api.UnmarshaledLocationAreas{}
+	err := c.get(pageUrl, &locationAreas)
+	return locationAreas, err
+}
+func (c *Client) LocationArea(name string) (api.UnmarshaledPokemonEncounters, error) {
+	pokemonEncounters := api.UnmarshaledPokemonEncounters{}
+	err := c.get(c.baseUrl + "location-area/" + name, &pokemonEncounters)
+	return pokemonEncounters, err
+}
+func (c *Client) Pokemon(name string) (api.UnmarshaledPokemonInfo, error) {
+	pokemonData := api.UnmarshaledPokemonInfo{}
+	err := c.get(c.baseUrl + "pokemon/" + name, &pokemonData)
+	return pokemonData, err
+}
+func (c *Client) PokemonSpecies(name string) (api.UnmarshaledPokemonSpecies, error) {
+	speciesData := api.UnmarshaledPokemonSpecies{}
+	err := c.get(c.baseUrl + "pokemon-species/" + name, &speciesData)
+	return speciesData, err
+}
+
+// PokemonWithSpecies fetches both endpoints and copies the species-only fields
+// (happiness, capture rate, pokedex entry) onto the pokemon data
+func (c *Client) PokemonWithSpecies(name string) (api.UnmarshaledPokemonInfo, error) {
+	pokemonData, err := c.Pokemon(name)
+	if err != nil {
+		return api.UnmarshaledPokemonInfo{}, err
+	}
+	speciesData, err := c.PokemonSpecies(name)
+	if err != nil {
+		return api.UnmarshaledPokemonInfo{}, err
+	}
+	pokemonData.BaseHappiness = speciesData.BaseHappiness
+	pokemonData.CaptureRate = speciesData.CaptureRate
+	pokemonData.GrowthRate = speciesData.GrowthRate.Name
+	if len(speciesData.FlavorText) > 0 {
+		pokemonData.EntryDescr = speciesData.FlavorText[0].EntryDescr
+	}
+	return pokemonData, nil
+}
+// EvolutionChain takes the chain url a species links to
+func (c *Client) EvolutionChain(chainUrl string) (api.UnmarshaledEvolutionChain, error) {
+	chainData := api.UnmarshaledEvolutionChain{}
+	err := c.get(chainUrl, &chainData)
+	return chainData, err
+}
+
+// EvolutionChainFor fetches the evolution chain the species belongs to
+func (c *Client) EvolutionChainFor(species string) (api.UnmarshaledEvolutionChain, error) {
+	speciesData, err := c.PokemonSpecies(species)
+	if err != nil {
+		return api.UnmarshaledEvolutionChain{}, err
+	}
+	if speciesData.EvolutionChain.Url == "" {
+		return api.UnmarshaledEvolutionChain{}, fmt.Errorf("%s has no evolution chain", species)
+	}
+	return c.EvolutionChain(speciesData.EvolutionChain.Url)
+}
+func (c *Client) Move(name string) (*api.MoveDetail, error) {
+	moveDetailData := api.MoveDetail{}
+	err := c.get(c.baseUrl + "move/" + name, &moveDetailData)
+	if err != nil {
+		return nil, err
+	}
+	return &moveDetailData, nil
+}
+
+// Type accepts either the type name or its numeric id
+func (c *Client) Type(nameOrId string) (api.TypeRelationsUnmarshal, error) {
+	typeRelationsUnmarshal := api.TypeRelationsUnmarshal{}
+	err := c.get(c.baseUrl + "type/" + nameOrId, &typeRelationsUnmarshal)
+	return typeRelationsUnmarshal, err
+}
+
+func (c *Client) get(url string, target any) error {
+	body, cached := c.cacheGet(url)
+	if !cached {
+		resp, err := c.httpClient.Get(url)
+		if err != nil {
+			return fmt.Errorf("sending get request to %s: %w", url, err)
+		}
+		defer resp.Body.Close()
+		if resp.StatusCode == http.StatusNotFound {
+			return fmt.Errorf("%s: %w", url, ErrNotFound)
+		}
+		if resp.StatusCode > 299 {
+			return fmt.Errorf("get request to %s failed with status code: %d", url, resp.StatusCode)
+		}
+		body, err = io.ReadAll(resp.Body)
+		if err != nil {
+			return fmt.Errorf("reading response body from %s: %w", url, err)
+		}
+	}
+	err := json.Unmarshal(body, target)
+	if err != nil {
+		return fmt.Errorf("processing json response from %s: %w", url, err)
+	}
+	// only cache bodies we know parse so a bad response is retried next time
+	if !cached && c.cache != nil {
+		c.cache.Add(url, body)
+	}
+	return nil
+}
+func (c *Client) cacheGet(url string) ([]byte, bool) {
+	if c.cache == nil {
+		return nil, false
+	}
+	return c.cache.Get(url)
+}
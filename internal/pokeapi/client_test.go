@@ -0,0 +1,117 @@
+package pokeapi
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/rashadat1/goPokedex/internal/pokecache"
+)
+
+func newTestServer(t *testing.T, hits map[string]int) *httptest.Server {
+	responses := map[string]string{
+		"/pokemon/pikachu": `{"base_experience": 112, "weight": 60, "types": [{"slot": 1, "type": {"name": "electric"}}]}`,
+		"/pokemon-species/pikachu": `{"base_happiness": 50, "capture_rate": 190, "growth_rate": {"name": "medium", "url": ""}, "evolution_chain": {"url": "{server}/evolution-chain/10/"}, "flavor_text_entries": [{"flavor_text": "When several of\nthese POKéMON gather"}]}`,
+		"/evolution-chain/10/": `{"id": 10, "chain": {"species": {"name": "pichu"}, "evolution_details": [], "evolves_to": [{"species": {"name": "pikachu"}, "evolution_details": [{"trigger": {"name": "level-up"}, "min_level": null, "min_happiness": 160, "item": null}], "evolves_to": [{"species": {"name": "raichu"}, "evolution_details": [{"trigger": {"name": "use-item"}, "item": {"name": "thunder-stone"}}], "evolves_to": []}]}]}}`,
+		"/move/thunderbolt": `{"name": "thunderbolt", "power": 90, "pp": 15, "accuracy": 100, "type": {"name": "electric"}}`,
+		"/type/13": `{"name": "electric", "damage_relations": {"double_damage_to": [{"name": "water"}]}}`,
+		"/broken": `{"name": `,
+	}
+	var server *httptest.Server
+	server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits[r.URL.Path]++
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		// links to other resources are absolute urls like the api sends
+		w.Write([]byte(strings.ReplaceAll(body, "{server}", server.URL)))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func TestClientEndpoints(t *testing.T) {
+	hits := make(map[string]int)
+	server := newTestServer(t, hits)
+	cache := pokecache.NewCache(time.Minute)
+	t.Cleanup(cache.Close)
+	client := NewClient(cache)
+	client.SetBaseUrl(server.URL)
+
+	pokemonData, err := client.PokemonWithSpecies("pikachu")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if pokemonData.BaseExp != 112 || pokemonData.CaptureRate != 190 || pokemonData.BaseHappiness != 50 || pokemonData.GrowthRate != "medium" {
+		t.Errorf("species fields were not copied onto pokemon data: %+v", pokemonData)
+	}
+	move, err := client.Move("thunderbolt")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if move.Power != 90 || move.Type.Name != "electric" {
+		t.Errorf("Got %+v expected thunderbolt with power 90", move)
+	}
+	chainData, err := client.EvolutionChainFor("pikachu")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	pikachu := chainData.Chain.EvolvesTo[0]
+	if chainData.Id != 10 || pikachu.Species.Name != "pikachu" || pikachu.EvolutionDetails[0].MinHappiness != 160 || pikachu.EvolvesTo[0].EvolutionDetails[0].Item.Name != "thunder-stone" {
+		t.Errorf("Got %+v expected the pichu, pikachu, raichu chain", chainData)
+	}
+	typeData, err := client.Type("13")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if typeData.Name != "electric" || len(typeData.DamageRelations.DoubleDmgTo) != 1 {
+		t.Errorf("Got %+v expected electric type relations", typeData)
+	}
+}
+
+func TestClientUsesCache(t *testing.T) {
+	hits := make(map[string]int)
+	server := newTestServer(t, hits)
+	cache := pokecache.NewCache(time.Minute)
+	t.Cleanup(cache.Close)
+	client := NewClient(cache)
+	client.SetBaseUrl(server.URL)
+
+	for i := 0; i < 3; i++ {
+		if _, err := client.Move("thunderbolt"); err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+	if hits["/move/thunderbolt"] != 1 {
+		t.Errorf("Got %d requests to the server expected 1", hits["/move/thunderbolt"])
+	}
+}
+
+func TestClientErrors(t *testing.T) {
+	hits := make(map[string]int)
+	server := newTestServer(t, hits)
+	cache := pokecache.NewCache(time.Minute)
+	t.Cleanup(cache.Close)
+	client := NewClient(cache)
+	client.SetBaseUrl(server.URL)
+
+	_, err := client.Pokemon("missingno")
+	if !errors.Is(err, ErrNotFound) {
+		t.Errorf("Got %v expected ErrNotFound", err)
+	}
+
+	var target struct{}
+	for i := 0; i < 2; i++ {
+		if err := client.get(server.URL + "/broken", &target); err == nil {
+			t.Errorf("expected an error decoding a malformed body")
+		}
+	}
+	if hits["/broken"] != 2 {
+		t.Errorf("malformed responses should not be cached - got %d requests expected 2", hits["/broken"])
+	}
+}
@@ -0,0 +1,211 @@
+package pokecache
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DiskStore is the persistent tier behind Cache - one content-addressed file per key
+// (the sha256 of the key) so responses survive restarts and the repl works offline once warmed
+type DiskStore struct {
+	dir            string
+	ttl            time.Duration
+	maxBytes       int64
+	mut            *sync.Mutex
+}
+type diskEntry struct {
+	Key            string `json:"key"`
+	CreatedAt      time.Time `json:"created_at"`
+	ExpiresAt      time.Time `json:"expires_at"`
+	Val            []byte `json:"val"`
+}
+
+const diskEntryExt = ".json"
+// entries are written to a temp file first, one older than staleTempAge was left behind by a crash
+const diskTempExt = ".tmp"
+const staleTempAge = time.Minute
+
+// NewDiskStore opens (creating if needed) a store in dir. ttl is the default lifetime of an entry
+// and maxBytes the budget for the whole directory - least recently used files go first once it is exceeded
+func NewDiskStore(dir string, ttl time.Duration, maxBytes int64) (*DiskStore, error) {
+	err := os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return nil, fmt.Errorf("creating cache directory %s: %w", dir, err)
+	}
+	store := DiskStore{dir: dir, ttl: ttl, maxBytes: maxBytes, mut: &sync.Mutex{}}
+	store.Prune()
+	return &store, nil
+}
+
+func (d *DiskStore) Get(key string) ([]byte, bool) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	path := d.pathFor(key)
+	entry, err := readDiskEntry(path)
+	if err != nil || entry.Key != key {
+		return nil, false
+	}
+	now := time.Now().UTC()
+	if !entry.ExpiresAt.IsZero() && now.After(entry.ExpiresAt) {
+		os.Remove(path)
+		return nil, false
+	}
+	// touch the file so the size budget evicts least recently used entries rather than oldest written
+	os.Chtimes(path, now, now)
+	return entry.Val, true
+}
+
+// Put stores val with the store's default ttl
+func (d *DiskStore) Put(key string, val []byte) error {
+	return d.PutWithTTL(key, val, d.ttl)
+}
+
+// PutWithTTL stores val under key, a ttl of zero means the entry never expires
+func (d *DiskStore) PutWithTTL(key string, val []byte, ttl time.Duration) error {
+	now := time.Now().UTC()
+	entry := diskEntry{Key: key, CreatedAt: now, Val: val}
+	if ttl > 0 {
+		entry.ExpiresAt = now.Add(ttl)
+	}
+	data, err := json.Marshal(entry)
+	if err != nil {
+		return fmt.Errorf("encoding cache entry for %s: %w", key, err)
+	}
+
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	path := d.pathFor(key)
+	// write to a temp file then rename so a crash never leaves a half written entry behind
+	tmp, err := os.CreateTemp(d.dir, "tmp-*" + diskTempExt)
+	if err != nil {
+		return fmt.Errorf("creating temp file in %s: %w", d.dir, err)
+	}
+	_, err = tmp.Write(data)
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing cache entry for %s: %w", key, err)
+	}
+	err = os.Rename(tmp.Name(), path)
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing cache entry for %s: %w", key, err)
+	}
+	d.enforceBudget()
+	return nil
+}
+
+func (d *DiskStore) Delete(key string) {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	os.Remove(d.pathFor(key))
+}
+
+// Prune removes expired entries, anything unreadable and temp files a crashed write left behind, then
+// trims the directory to the size budget
+func (d *DiskStore) Prune() {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	now := time.Now().UTC()
+	files, err := os.ReadDir(d.dir)
+	if err != nil {
+		return
+	}
+	for _, file := range files {
+		path := filepath.Join(d.dir, file.Name())
+		if !file.IsDir() && strings.HasSuffix(file.Name(), diskTempExt) {
+			// a younger one may still be being written by another process sharing the directory
+			if info, err := file.Info(); err == nil && now.Sub(info.ModTime()) > staleTempAge {
+				os.Remove(path)
+			}
+			continue
+		}
+		if file.IsDir() || !strings.HasSuffix(file.Name(), diskEntryExt) {
+			continue
+		}
+		entry, err := readDiskEntry(path)
+		if err != nil || (!entry.ExpiresAt.IsZero() && now.After(entry.ExpiresAt)) {
+			os.Remove(path)
+		}
+	}
+	d.enforceBudget()
+}
+
+// Size reports the number of bytes currently used by entries on disk
+func (d *DiskStore) Size() int64 {
+	d.mut.Lock()
+	defer d.mut.Unlock()
+	var total int64
+	for _, info := range d.entryInfos() {
+		total += info.Size()
+	}
+	return total
+}
+
+// must be called with d.mut held
+func (d *DiskStore) enforceBudget() {
+	if d.maxBytes <= 0 {
+		return
+	}
+	infos := d.entryInfos()
+	var total int64
+	for _, info := range infos {
+		total += info.Size()
+	}
+	if total <= d.maxBytes {
+		return
+	}
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().Before(infos[j].ModTime())
+	})
+	for _, info := range infos {
+		if total <= d.maxBytes {
+			break
+		}
+		if os.Remove(filepath.Join(d.dir, info.Name())) == nil {
+			total -= info.Size()
+		}
+	}
+}
+func (d *DiskStore) entryInfos() []os.FileInfo {
+	files, err := os.ReadDir(d.dir)
+	if err != nil {
+		return nil
+	}
+	infos := []os.FileInfo{}
+	for _, file := range files {
+		if file.IsDir() || !strings.HasSuffix(file.Name(), diskEntryExt) {
+			continue
+		}
+		info, err := file.Info()
+		if err != nil {
+			continue
+		}
+		infos = append(infos, info)
+	}
+	return infos
+}
+func (d *DiskStore) pathFor(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:]) + diskEntryExt)
+}
+func readDiskEntry(path string) (diskEntry, error) {
+	entry := diskEntry{}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return entry, err
+	}
+	err = json.Unmarshal(data, &entry)
+	return entry, err
+}
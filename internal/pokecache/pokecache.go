@@ -1,45 +1,198 @@
 package pokecache
 
 import (
+	"container/list"
+	"strings"
 	"sync"
 	"time"
 )
 
 type cacheEntry struct {
 	createdAt      time.Time
+	expiresAt      time.Time // zero value means the entry never expires
 	val            []byte
+	element        *list.Element // position of the key in the lru list
+}
+type ttlPolicy struct {
+	prefix         string
+	ttl            time.Duration
+}
+// Stats is a snapshot of the cache counters
+type Stats struct {
+	Hits           int
+	Misses         int
+	Evictions      int // entries dropped to stay under capacity
+	Expirations    int // entries dropped because their ttl ran out
+	Entries        int
 }
 type Cache struct {
-	entryMap       map[string]cacheEntry
-	mut            *sync.Mutex     
+	entryMap       map[string]*cacheEntry
+	lru            *list.List // front is the most recently used key
+	capacity       int // 0 means unbounded
+	defaultTTL     time.Duration
+	ttlPolicies    []ttlPolicy
+	stats          Stats
+	mut            *sync.Mutex
+	disk           *DiskStore // optional persistent tier, nil for a memory only cache
+	done           chan struct{}
+	reaperStopped  chan struct{}
+	closeOnce      *sync.Once
 }
 
+// Add stores val using the ttl of the longest matching prefix policy, or the cache's default ttl.
+// A bounded policy only limits the memory tier, on disk the entry keeps the store's own (longer) ttl
+// so it is still there offline. A policy that never expires applies to both
 func (c *Cache) Add(key string, val []byte) {
+	ttl, hasPolicy := c.ttlFor(key)
+	c.add(key, val, ttl)
+	if c.disk != nil {
+		// a failed disk write only costs us a refetch after restart so the memory entry is enough
+		if hasPolicy && ttl <= 0 {
+			c.disk.PutWithTTL(key, val, ttl)
+		} else {
+			c.disk.Put(key, val)
+		}
+	}
+}
+
+// AddWithTTL stores val for ttl in every tier, a ttl <= 0 means the entry never expires
+func (c *Cache) AddWithTTL(key string, val []byte, ttl time.Duration) {
+	c.add(key, val, ttl)
+	if c.disk != nil {
+		c.disk.PutWithTTL(key, val, ttl)
+	}
+}
+func (c *Cache) Get(key string) ([]byte, bool) {
 	c.mut.Lock()
-	newEntry := cacheEntry{createdAt: time.Now().UTC(), val: val}
-	c.entryMap[key] = newEntry
+	retrievedCacheEntry, ok := c.entryMap[key]
+	if ok && retrievedCacheEntry.expired(time.Now().UTC()) {
+		c.remove(key, retrievedCacheEntry)
+		c.stats.Expirations++
+		ok = false
+	}
+	if ok {
+		c.lru.MoveToFront(retrievedCacheEntry.element)
+		c.stats.Hits++
+		c.mut.Unlock()
+		return retrievedCacheEntry.val, true
+	}
+	c.mut.Unlock()
+
+	if c.disk != nil {
+		if val, ok := c.disk.Get(key); ok {
+			// promote the disk hit back into memory
+			ttl, _ := c.ttlFor(key)
+			c.add(key, val, ttl)
+			c.mut.Lock()
+			c.stats.Hits++
+			c.mut.Unlock()
+			return val, true
+		}
+	}
+	c.mut.Lock()
+	c.stats.Misses++
 	c.mut.Unlock()
+	return nil, false
 }
-func (c *Cache) Get(key string) ([]byte, bool) {
+
+// SetTTLPolicy makes every key starting with prefix live for ttl (<= 0 for forever) - e.g. types and
+// moves never change so they can outlive the short default used for paginated location areas
+func (c *Cache) SetTTLPolicy(prefix string, ttl time.Duration) {
 	c.mut.Lock()
 	defer c.mut.Unlock()
-	retrievedCacheEntry, ok := c.entryMap[key]
-	if !ok {
-		return nil, false
+	for i := range c.ttlPolicies {
+		if c.ttlPolicies[i].prefix == prefix {
+			c.ttlPolicies[i].ttl = ttl
+			return
+		}
+	}
+	c.ttlPolicies = append(c.ttlPolicies, ttlPolicy{prefix: prefix, ttl: ttl})
+}
+
+// SetCapacity bounds the number of in-memory entries, evicting the least recently used ones
+// when it is exceeded. A capacity of 0 removes the bound
+func (c *Cache) SetCapacity(capacity int) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	c.capacity = capacity
+	c.evictOverCapacity()
+}
+func (c *Cache) Stats() Stats {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	stats := c.stats
+	stats.Entries = len(c.entryMap)
+	return stats
+}
+
+func (c *Cache) add(key string, val []byte, ttl time.Duration) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	now := time.Now().UTC()
+	newEntry := &cacheEntry{createdAt: now, val: val}
+	if ttl > 0 {
+		newEntry.expiresAt = now.Add(ttl)
+	}
+	if oldEntry, ok := c.entryMap[key]; ok {
+		newEntry.element = oldEntry.element
+		c.lru.MoveToFront(newEntry.element)
+	} else {
+		newEntry.element = c.lru.PushFront(key)
+	}
+	c.entryMap[key] = newEntry
+	c.evictOverCapacity()
+}
+// must be called with c.mut held
+func (c *Cache) evictOverCapacity() {
+	if c.capacity <= 0 {
+		return
+	}
+	for len(c.entryMap) > c.capacity {
+		oldest := c.lru.Back()
+		key := oldest.Value.(string)
+		c.remove(key, c.entryMap[key])
+		c.stats.Evictions++
+	}
+}
+// must be called with c.mut held
+func (c *Cache) remove(key string, entry *cacheEntry) {
+	c.lru.Remove(entry.element)
+	delete(c.entryMap, key)
+}
+func (c *Cache) ttlFor(key string) (time.Duration, bool) {
+	c.mut.Lock()
+	defer c.mut.Unlock()
+	matched := -1
+	for i, policy := range c.ttlPolicies {
+		if strings.HasPrefix(key, policy.prefix) && (matched == -1 || len(policy.prefix) > len(c.ttlPolicies[matched].prefix)) {
+			matched = i
+		}
+	}
+	if matched == -1 {
+		return c.defaultTTL, false
 	}
-	return retrievedCacheEntry.val, true
+	return c.ttlPolicies[matched].ttl, true
+}
+func (e *cacheEntry) expired(now time.Time) bool {
+	return !e.expiresAt.IsZero() && now.After(e.expiresAt)
 }
+
 func (c *Cache) reapLoop(interval time.Duration) {
 	ticker := time.NewTicker(interval)
+	defer close(c.reaperStopped)
+	defer ticker.Stop()
 	for {
 		select {
+		case <-c.done:
+			return
 		case _ = <-ticker.C:
 			c.mut.Lock()
 			// remove logic here for expired cache entries
 			currTimestamp := time.Now().UTC()
 			for key, val := range c.entryMap {
-				if currTimestamp.Sub(val.createdAt) > interval {
-					delete(c.entryMap, key)
+				if val.expired(currTimestamp) {
+					c.remove(key, val)
+					c.stats.Expirations++
 				}
 			}
 			c.mut.Unlock()
@@ -47,11 +200,38 @@ func (c *Cache) reapLoop(interval time.Duration) {
 	}
 }
 
+// NewCache reaps expired entries every interval, interval is also the default ttl for entries
+// that have no ttl of their own
 func NewCache(interval time.Duration) *Cache {
-	entries := make(map[string]cacheEntry)
+	entries := make(map[string]*cacheEntry)
 	mutex := sync.Mutex{}
-	cache := Cache{entryMap: entries, mut: &mutex}
-	
+	cache := Cache{
+		entryMap: entries,
+		lru: list.New(),
+		defaultTTL: interval,
+		mut: &mutex,
+		done: make(chan struct{}),
+		reaperStopped: make(chan struct{}),
+		closeOnce: &sync.Once{},
+	}
+
 	go cache.reapLoop(interval)
 	return &cache
 }
+
+// Close stops the reaper goroutine and its ticker and waits for it to exit. It is safe to call more
+// than once - the cache stays usable afterwards, expired entries are then only dropped lazily by Get
+func (c *Cache) Close() {
+	c.closeOnce.Do(func() {
+		close(c.done)
+	})
+	<-c.reaperStopped
+}
+
+// NewCacheWithDisk returns a cache whose entries are also written to disk - memory entries are still
+// reaped every interval but Get falls back to the disk store until the entry's own ttl runs out
+func NewCacheWithDisk(interval time.Duration, disk *DiskStore) *Cache {
+	cache := NewCache(interval)
+	cache.disk = disk
+	return cache
+}
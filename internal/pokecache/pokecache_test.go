@@ -0,0 +1,192 @@
+package pokecache
+
+import (
+	"os"
+	"path/filepath"
+	"runtime"
+	"testing"
+	"time"
+)
+
+func TestDiskStoreSurvivesRestart(t *testing.T) {
+	dir := t.TempDir()
+	disk, err := NewDiskStore(dir, time.Hour, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	cache := NewCacheWithDisk(time.Minute, disk)
+	defer cache.Close()
+	cache.Add("https://pokeapi.co/api/v2/move/tackle", []byte("tackle"))
+
+	// a brand new cache over the same directory stands in for a restarted repl
+	reopened, err := NewDiskStore(dir, time.Hour, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	restarted := NewCacheWithDisk(time.Minute, reopened)
+	defer restarted.Close()
+	val, ok := restarted.Get("https://pokeapi.co/api/v2/move/tackle")
+	if !ok || string(val) != "tackle" {
+		t.Errorf("Got %q, %v expected tackle to be read back from disk", val, ok)
+	}
+}
+
+func TestBoundedPolicyOnlyLimitsMemory(t *testing.T) {
+	disk, err := NewDiskStore(t.TempDir(), time.Hour, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	cache := NewCacheWithDisk(time.Minute, disk)
+	defer cache.Close()
+	cache.SetTTLPolicy("https://pokeapi.co/api/v2/pokemon/", time.Millisecond)
+	cache.Add("https://pokeapi.co/api/v2/pokemon/pikachu", []byte("pikachu"))
+	time.Sleep(5 * time.Millisecond)
+
+	// the memory entry is gone but the disk copy keeps the store's hour
+	if _, ok := disk.Get("https://pokeapi.co/api/v2/pokemon/pikachu"); !ok {
+		t.Fatalf("expected the disk entry to outlive the memory ttl")
+	}
+	val, ok := cache.Get("https://pokeapi.co/api/v2/pokemon/pikachu")
+	if !ok || string(val) != "pikachu" || cache.Stats().Expirations != 1 {
+		t.Errorf("Got %q, %v with stats %+v expected an expired memory entry served from disk", val, ok, cache.Stats())
+	}
+}
+
+func TestDiskStoreExpiryAndBudget(t *testing.T) {
+	disk, err := NewDiskStore(t.TempDir(), time.Hour, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	disk.PutWithTTL("expired", []byte("old"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+	if _, ok := disk.Get("expired"); ok {
+		t.Errorf("expected expired entry to be dropped")
+	}
+
+	disk.Put("first", make([]byte, 100))
+	size := disk.Size()
+	disk.maxBytes = size + size / 2
+	// make sure modification times differ so eviction order is well defined
+	time.Sleep(10 * time.Millisecond)
+	disk.Put("second", make([]byte, 100))
+	if _, ok := disk.Get("first"); ok {
+		t.Errorf("expected least recently used entry to be evicted once over budget")
+	}
+	if _, ok := disk.Get("second"); !ok {
+		t.Errorf("expected newest entry to survive eviction")
+	}
+}
+
+func TestDiskStorePrunesStaleTempFiles(t *testing.T) {
+	dir := t.TempDir()
+	disk, err := NewDiskStore(dir, time.Hour, 0)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	disk.Put("kept", []byte("entry"))
+	if temps, _ := filepath.Glob(filepath.Join(dir, "*" + diskTempExt)); len(temps) != 0 {
+		t.Errorf("Got %v expected a finished write to leave no temp file", temps)
+	}
+
+	// one left by a crash an hour ago and one another process is still writing
+	stale := filepath.Join(dir, "tmp-crashed" + diskTempExt)
+	fresh := filepath.Join(dir, "tmp-writing" + diskTempExt)
+	for _, path := range []string{stale, fresh} {
+		err = os.WriteFile(path, []byte("half an entr"), 0o644)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+	}
+	hourAgo := time.Now().Add(-time.Hour)
+	os.Chtimes(stale, hourAgo, hourAgo)
+	disk.Prune()
+	if _, err := os.Stat(stale); !os.IsNotExist(err) {
+		t.Errorf("expected the stale temp file to be deleted")
+	}
+	if _, err := os.Stat(fresh); err != nil {
+		t.Errorf("expected a temp file still being written to be left alone")
+	}
+	if _, ok := disk.Get("kept"); !ok {
+		t.Errorf("expected entries to survive pruning temp files")
+	}
+}
+
+func TestCacheTTLPolicies(t *testing.T) {
+	cache := NewCache(time.Hour)
+	defer cache.Close()
+	cache.SetTTLPolicy("https://pokeapi.co/api/v2/", time.Nanosecond)
+	cache.SetTTLPolicy("https://pokeapi.co/api/v2/type/", 0)
+	cache.Add("https://pokeapi.co/api/v2/type/1", []byte("normal"))
+	cache.Add("https://pokeapi.co/api/v2/location-area/1", []byte("canalave-city-area"))
+	cache.AddWithTTL("custom", []byte("custom"), time.Nanosecond)
+	time.Sleep(time.Millisecond)
+
+	if _, ok := cache.Get("https://pokeapi.co/api/v2/type/1"); !ok {
+		t.Errorf("expected the longest matching prefix policy (never expire) to apply")
+	}
+	if _, ok := cache.Get("https://pokeapi.co/api/v2/location-area/1"); ok {
+		t.Errorf("expected location area entry to have expired")
+	}
+	if _, ok := cache.Get("custom"); ok {
+		t.Errorf("expected per-key ttl entry to have expired")
+	}
+	stats := cache.Stats()
+	if stats.Hits != 1 || stats.Misses != 2 || stats.Expirations != 2 {
+		t.Errorf("Got %+v expected 1 hit, 2 misses and 2 expirations", stats)
+	}
+}
+
+func TestCacheLRUEviction(t *testing.T) {
+	cache := NewCache(time.Hour)
+	defer cache.Close()
+	cache.SetCapacity(2)
+	cache.Add("a", []byte("a"))
+	cache.Add("b", []byte("b"))
+	cache.Get("a")
+	cache.Add("c", []byte("c"))
+
+	if _, ok := cache.Get("b"); ok {
+		t.Errorf("expected least recently used key b to be evicted")
+	}
+	for _, key := range []string{"a", "c"} {
+		if _, ok := cache.Get(key); !ok {
+			t.Errorf("expected %s to still be cached", key)
+		}
+	}
+	stats := cache.Stats()
+	if stats.Evictions != 1 || stats.Entries != 2 {
+		t.Errorf("Got %+v expected 1 eviction and 2 entries", stats)
+	}
+}
+
+func TestCloseStopsReaper(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+	caches := []*Cache{}
+	for i := 0; i < 50; i++ {
+		caches = append(caches, NewCache(time.Millisecond))
+	}
+	if runtime.NumGoroutine() < baseline + 50 {
+		t.Fatalf("expected every cache to start a reaper goroutine")
+	}
+	for _, cache := range caches {
+		cache.Close()
+	}
+	// Close waits for the reaper to return, but the runtime may take a moment to retire the goroutine
+	deadline := time.Now().Add(time.Second)
+	for runtime.NumGoroutine() > baseline && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	if leaked := runtime.NumGoroutine() - baseline; leaked > 0 {
+		t.Errorf("Got %d leaked goroutines after closing every cache expected 0", leaked)
+	}
+}
+
+func TestCloseIsIdempotentAndCacheStaysUsable(t *testing.T) {
+	cache := NewCache(time.Hour)
+	cache.Close()
+	cache.Close()
+	cache.Add("key", []byte("val"))
+	if val, ok := cache.Get("key"); !ok || string(val) != "val" {
+		t.Errorf("Got %q, %v expected closed cache to keep serving entries", val, ok)
+	}
+}
@@ -0,0 +1,163 @@
+package pokemongenerator
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/experience"
+	"github.com/rashadat1/goPokedex/internal/natures"
+	"github.com/rashadat1/goPokedex/internal/pokeapi"
+	"github.com/rashadat1/goPokedex/internal/statCalculator"
+)
+
+const MaxIV = 31
+
+// returned (wrapped, with every problem spelled out) when a spec describes a pokemon that can't exist
+var ErrIllegalSet = errors.New("illegal pokemon set")
+
+// Spec is an exact pokemon to build, like a competitive set. IVs left out are 31, EVs left out are 0,
+// an empty nature is hardy and an empty ability is the species' first one
+type Spec struct {
+	Species          string
+	Level            int
+	Nature           string
+	Ability          string
+	IVs              map[string]int
+	EVs              map[string]int
+	Moves            []string
+}
+
+// Build makes the pokemon the spec describes, checking the set against the species' abilities and
+// the learnset from CreateLearnset first
+func Build(client *pokeapi.Client, spec Spec) (api.Pokemon, error) {
+	pokemonData, err := client.PokemonWithSpecies(spec.Species)
+	if err != nil {
+		return api.Pokemon{}, fmt.Errorf("fetching pokemon data for %s: %w", spec.Species, err)
+	}
+	spec = withDefaults(spec, pokemonData)
+	err = ValidateSpec(spec, pokemonData)
+	if err != nil {
+		return api.Pokemon{}, err
+	}
+	pokemonInstance, err := newPokemon(spec.Species, pokemonData, spec.Level, spec.Nature, spec.Ability, spec.IVs, spec.EVs)
+	if err != nil {
+		return api.Pokemon{}, err
+	}
+	pokemonInstance.Moves, err = loadMoves(client, spec.Moves)
+	if err != nil {
+		return api.Pokemon{}, err
+	}
+	return pokemonInstance, nil
+}
+
+// withDefaults fills in what the spec leaves out
+func withDefaults(spec Spec, pokemonData api.UnmarshaledPokemonInfo) Spec {
+	if spec.Nature == "" {
+		spec.Nature = "hardy"
+	}
+	if spec.Ability == "" && len(pokemonData.Abilities) > 0 {
+		spec.Ability = pokemonData.Abilities[0].Ability.Name
+	}
+	ivs := make(map[string]int, len(statCalculator.StatNames))
+	evs := make(map[string]int, len(statCalculator.StatNames))
+	for _, stat := range statCalculator.StatNames {
+		ivs[stat] = MaxIV
+	}
+	for stat, iv := range spec.IVs {
+		ivs[stat] = iv
+	}
+	for stat, ev := range spec.EVs {
+		evs[stat] = ev
+	}
+	spec.IVs, spec.EVs = ivs, evs
+	return spec
+}
+
+// ValidateSpec checks a spec against the species data and reports every problem with it at once
+func ValidateSpec(spec Spec, pokemonData api.UnmarshaledPokemonInfo) error {
+	problems := []string{}
+	if spec.Level < 1 || spec.Level > experience.MaxLevel {
+		problems = append(problems, fmt.Sprintf("level %d is outside 1-%d", spec.Level, experience.MaxLevel))
+	}
+	if _, ok := natures.Get(spec.Nature); !ok {
+		problems = append(problems, fmt.Sprintf("unknown nature %q", spec.Nature))
+	}
+	abilities := []string{}
+	for _, abilityData := range pokemonData.Abilities {
+		abilities = append(abilities, abilityData.Ability.Name)
+	}
+	if !slices.Contains(abilities, spec.Ability) {
+		problems = append(problems, fmt.Sprintf("%s can't have the ability %q, expected one of %s", spec.Species, spec.Ability, strings.Join(abilities, ", ")))
+	}
+
+	totalEVs := 0
+	for _, stat := range sortedKeys(spec.IVs) {
+		if !slices.Contains(statCalculator.StatNames[:], stat) {
+			problems = append(problems, fmt.Sprintf("unknown stat %q in ivs", stat))
+		} else if iv := spec.IVs[stat]; iv < 0 || iv > MaxIV {
+			problems = append(problems, fmt.Sprintf("%s iv %d is outside 0-%d", stat, iv, MaxIV))
+		}
+	}
+	for _, stat := range sortedKeys(spec.EVs) {
+		ev := spec.EVs[stat]
+		totalEVs += ev
+		if !slices.Contains(statCalculator.StatNames[:], stat) {
+			problems = append(problems, fmt.Sprintf("unknown stat %q in evs", stat))
+		} else if ev < 0 || ev > statCalculator.MaxStatEVs {
+			problems = append(problems, fmt.Sprintf("%s ev %d is outside 0-%d", stat, ev, statCalculator.MaxStatEVs))
+		}
+	}
+	if totalEVs > statCalculator.MaxTotalEVs {
+		problems = append(problems, fmt.Sprintf("evs add up to %d, more than %d", totalEVs, statCalculator.MaxTotalEVs))
+	}
+
+	if len(spec.Moves) == 0 || len(spec.Moves) > 4 {
+		problems = append(problems, fmt.Sprintf("a pokemon knows 1 to 4 moves, got %d", len(spec.Moves)))
+	}
+	learnset := CreateLearnset(spec.Species, pokemonData)
+	for i, moveName := range spec.Moves {
+		if slices.Contains(spec.Moves[:i], moveName) {
+			problems = append(problems, fmt.Sprintf("%s is listed more than once", moveName))
+			continue
+		}
+		learnedAt, byLevel := levelLearned(learnset, moveName)
+		switch {
+		case byLevel && learnedAt <= spec.Level, learnableOtherwise(learnset, moveName):
+		case byLevel:
+			problems = append(problems, fmt.Sprintf("%s learns %s at level %d, above level %d", spec.Species, moveName, learnedAt, spec.Level))
+		default:
+			problems = append(problems, fmt.Sprintf("%s can't learn %s", spec.Species, moveName))
+		}
+	}
+	if len(problems) > 0 {
+		return fmt.Errorf("%w: %s", ErrIllegalSet, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// levelLearned is the lowest level the move is learned at by leveling up
+func levelLearned(learnset api.MoveList, moveName string) (int, bool) {
+	lowest, found := 0, false
+	for level, moves := range learnset.LevelUpMoves {
+		if slices.Contains(moves, moveName) && (!found || level < lowest) {
+			lowest, found = level, true
+		}
+	}
+	return lowest, found
+}
+
+// learnableOtherwise is true for egg, machine and tutor moves, which don't depend on the level
+func learnableOtherwise(learnset api.MoveList, moveName string) bool {
+	return slices.Contains(learnset.EggMoves, moveName) || slices.Contains(learnset.MachineMoves, moveName) || slices.Contains(learnset.TutorMoves, moveName)
+}
+func sortedKeys(values map[string]int) []string {
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	return keys
+}
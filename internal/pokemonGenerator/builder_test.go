@@ -0,0 +1,154 @@
+package pokemongenerator
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/pokeapi"
+)
+
+const pikachuJson = `{
+	"base_experience": 112,
+	"weight": 60,
+	"types": [{"slot": 1, "type": {"name": "electric"}}],
+	"abilities": [{"ability": {"name": "static"}, "is_hidden": false}, {"ability": {"name": "lightning-rod"}, "is_hidden": true}],
+	"stats": [
+		{"base_stat": 35, "effort": 0, "stat": {"name": "hp"}},
+		{"base_stat": 55, "effort": 0, "stat": {"name": "attack"}},
+		{"base_stat": 40, "effort": 0, "stat": {"name": "defense"}},
+		{"base_stat": 50, "effort": 0, "stat": {"name": "special-attack"}},
+		{"base_stat": 50, "effort": 0, "stat": {"name": "special-defense"}},
+		{"base_stat": 90, "effort": 2, "stat": {"name": "speed"}}
+	],
+	"moves": [
+		{"move": {"name": "thunder-shock"}, "version_group_details": [{"level_learned_at": 1, "move_learn_method": {"name": "level-up"}, "version_group": {"name": "scarlet-violet"}}]},
+		{"move": {"name": "thunder"}, "version_group_details": [{"level_learned_at": 44, "move_learn_method": {"name": "level-up"}, "version_group": {"name": "scarlet-violet"}}]},
+		{"move": {"name": "thunderbolt"}, "version_group_details": [{"level_learned_at": 36, "move_learn_method": {"name": "level-up"}, "version_group": {"name": "scarlet-violet"}}, {"level_learned_at": 0, "move_learn_method": {"name": "machine"}, "version_group": {"name": "scarlet-violet"}}]},
+		{"move": {"name": "volt-tackle"}, "version_group_details": [{"level_learned_at": 0, "move_learn_method": {"name": "egg"}, "version_group": {"name": "scarlet-violet"}}]},
+		{"move": {"name": "surf"}, "version_group_details": [{"level_learned_at": 0, "move_learn_method": {"name": "machine"}, "version_group": {"name": "lets-go-pikachu-lets-go-eevee"}}]}
+	]
+}`
+
+func pikachuData(t *testing.T) api.UnmarshaledPokemonInfo {
+	client := newTestClient(t)
+	pokemonData, err := client.PokemonWithSpecies("pikachu")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	return pokemonData
+}
+func newTestClient(t *testing.T) *pokeapi.Client {
+	responses := map[string]string{
+		"/pokemon/pikachu": pikachuJson,
+		"/pokemon-species/pikachu": `{"base_happiness": 50, "capture_rate": 190, "growth_rate": {"name": "medium"}}`,
+		"/move/thunderbolt": `{"name": "thunderbolt", "power": 90, "pp": 15, "accuracy": 100, "type": {"name": "electric"}}`,
+		"/move/volt-tackle": `{"name": "volt-tackle", "power": 120, "pp": 15, "accuracy": 100, "type": {"name": "electric"}}`,
+		"/move/thunder-shock": `{"name": "thunder-shock", "power": 40, "pp": 30, "accuracy": 100, "type": {"name": "electric"}}`,
+		"/move/thunder": `{"name": "thunder", "power": 110, "pp": 10, "accuracy": 70, "type": {"name": "electric"}}`,
+		"/move/surf": `{"name": "surf", "power": 90, "pp": 15, "accuracy": 100, "type": {"name": "water"}}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	t.Cleanup(server.Close)
+	client := pokeapi.NewClient(nil)
+	client.SetBaseUrl(server.URL)
+	return client
+}
+
+func TestValidateSpec(t *testing.T) {
+	pokemonData := pikachuData(t)
+	cases := []struct {
+		name        string
+		spec        Spec
+		problems    []string // empty for a legal set
+	}{
+		{name: "legal set", spec: Spec{Level: 50, Ability: "lightning-rod", Moves: []string{"thunderbolt", "volt-tackle", "thunder-shock"}, EVs: map[string]int{"speed": 252, "special-attack": 252, "hp": 4}}},
+		{name: "machine move above its level-up level", spec: Spec{Level: 5, Moves: []string{"thunderbolt"}}},
+		{name: "level-up move too early", spec: Spec{Level: 30, Moves: []string{"thunder"}}, problems: []string{"learns thunder at level 44"}},
+		{name: "move from another game", spec: Spec{Level: 50, Moves: []string{"surf"}}, problems: []string{"can't learn surf"}},
+		{name: "ability", spec: Spec{Level: 50, Ability: "levitate", Moves: []string{"thunderbolt"}}, problems: []string{`can't have the ability "levitate"`}},
+		{name: "nature and level", spec: Spec{Level: 101, Nature: "grumpy", Moves: []string{"thunderbolt"}}, problems: []string{"level 101", `unknown nature "grumpy"`}},
+		{name: "ivs and evs", spec: Spec{Level: 50, Moves: []string{"thunderbolt"}, IVs: map[string]int{"speed": 32, "luck": 1}, EVs: map[string]int{"speed": 252, "attack": 252, "hp": 252}}, problems: []string{"speed iv 32", `unknown stat "luck"`, "evs add up to 756"}},
+		{name: "moves", spec: Spec{Level: 50, Moves: []string{"thunderbolt", "thunderbolt", "thunder-shock", "volt-tackle", "thunder"}}, problems: []string{"1 to 4 moves, got 5", "thunderbolt is listed more than once"}},
+	}
+	for _, c := range cases {
+		c.spec.Species = "pikachu"
+		err := ValidateSpec(withDefaults(c.spec, pokemonData), pokemonData)
+		if len(c.problems) == 0 {
+			if err != nil {
+				t.Errorf("%s: Got %s expected a legal set", c.name, err.Error())
+			}
+			continue
+		}
+		if !errors.Is(err, ErrIllegalSet) {
+			t.Errorf("%s: Got %v expected an illegal set", c.name, err)
+			continue
+		}
+		for _, problem := range c.problems {
+			if !strings.Contains(err.Error(), problem) {
+				t.Errorf("%s: Got %q expected it to mention %q", c.name, err.Error(), problem)
+			}
+		}
+	}
+}
+
+func TestBuild(t *testing.T) {
+	spec := Spec{
+		Species: "pikachu",
+		Level: 50,
+		Nature: "timid",
+		IVs: map[string]int{"attack": 0},
+		EVs: map[string]int{"hp": 4, "special-attack": 252, "speed": 252},
+		Moves: []string{"thunderbolt", "volt-tackle"},
+	}
+	pokemon, err := Build(newTestClient(t), spec)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	// hp (70 + 31 + 1) * 50 / 100 + 60 = 111, attack (110 + 0) * 50 / 100 + 5 = 60 * 0.9 = 54 and
+	// speed (180 + 31 + 63) * 50 / 100 + 5 = 142 * 1.1 = 156
+	expected := map[string]int{"hp": 111, "attack": 54, "speed": 156}
+	for stat, value := range expected {
+		if pokemon.Stats[stat].StatValue != value {
+			t.Errorf("%s: Got %d expected %d", stat, pokemon.Stats[stat].StatValue, value)
+		}
+	}
+	if pokemon.CurrHp != 111 || pokemon.Ability != "static" || pokemon.Moves[1].Detail.Name != "volt-tackle" || pokemon.Moves[2] != nil || pokemon.Experience != 125000 {
+		t.Errorf("Got %+v expected a full hp level 50 pikachu with static and two moves", pokemon)
+	}
+}
+
+func TestGeneratePokemonIsSeeded(t *testing.T) {
+	client := newTestClient(t)
+	generate := func(seed int64) api.Pokemon {
+		pokemon, err := GeneratePokemon(client, "pikachu", 50, rand.New(rand.NewSource(seed)))
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err.Error())
+		}
+		return pokemon
+	}
+	first, second := generate(3), generate(3)
+	if fmt.Sprintf("%+v %v", first.Stats, first.Moves[0].Detail) != fmt.Sprintf("%+v %v", second.Stats, second.Moves[0].Detail) || first.Nature != second.Nature || first.Ability != second.Ability {
+		t.Errorf("Got %+v and %+v expected the same seed to generate the same pokemon", first, second)
+	}
+	if first.CurrHp != first.Stats["hp"].StatValue || first.Stats["speed"].BaseStat != 90 || first.Experience != 125000 {
+		t.Errorf("Got %+v expected a full hp level 50 pikachu", first)
+	}
+	for _, stat := range first.Stats {
+		if stat.EVValue != 0 || stat.IVValue > MaxIV {
+			t.Errorf("Got %+v expected no evs and ivs up to %d", stat, MaxIV)
+		}
+	}
+}
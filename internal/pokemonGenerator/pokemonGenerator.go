@@ -1,197 +1,156 @@
 package pokemongenerator
 
 import (
-	"encoding/json"
 	"fmt"
-	"io"
 	"math/rand"
-	"net/http"
 	"slices"
 	"strings"
-	"time"
 
 	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/experience"
+	"github.com/rashadat1/goPokedex/internal/natures"
+	"github.com/rashadat1/goPokedex/internal/pokeapi"
 	"github.com/rashadat1/goPokedex/internal/statCalculator"
 )
 
 // we want to take a pokemon species, map of evs, map of ivs, level
 
 
-
-var moveCache = make(map[string]*api.MoveDetail)
-
-func GeneratePokemon(species string, level int) (api.Pokemon, error) {
+func GeneratePokemon(client *pokeapi.Client, species string, level int, rng *rand.Rand) (api.Pokemon, error) {
 	// method to generate new instance of pokemon - create wild and npc pokemon
-	// nature, evs, ivs are random (evs should be zero for wild pokemon)
+	// nature, evs, ivs are random (evs should be zero for wild pokemon) and drawn from rng
+	// so the same seed always generates the same pokemon
 	// use species to get base 
-	newSource := rand.NewSource(time.Now().UnixNano())
-	rand := rand.New(newSource)
-	statNames := [6]string{"hp", "attack", "defense", "special-attack", "special-defense", "speed"}
-	natures := [25]string{
-		"Hardy",
-		"Lonely",
-		"Brave",
-		"Adamant",
-		"Naughty",
-		"Bold",
-		"Docile",
-		"Relaxed",
-		"Impish",
-		"Lax",
-		"Timid",
-		"Hasty",
-		"Serious",
-		"Jolly",
-		"Naive",
-		"Modest",
-		"Mild",
-		"Quiet",
-		"Bashful",
-		"Rash",
-		"Calm",
-		"Gentle",
-		"Sassy",
-		"Careful",
-		"Quirky",
-	}
-	basePokemonUrl := "https://pokeapi.co/api/v2/pokemon/"
-	baseSpeciesUrl := "https://pokeapi.co/api/v2/pokemon-species/"
-
-	respPokemon, err := http.Get(basePokemonUrl + species)
+	pokemonData, err := client.PokemonWithSpecies(species)
 	if err != nil {
-		fmt.Printf("Error sending Get Request to Pokemon Endpoint: %s\n", err.Error())
-		return api.Pokemon{}, err
+		return api.Pokemon{}, fmt.Errorf("fetching pokemon data for %s: %w", species, err)
 	}
-	if respPokemon.StatusCode > 299 {
-		if respPokemon.StatusCode == 404 {
-			fmt.Printf("%s is not a Pokemon - please choose a valid Pokemon\n", species)
-			return api.Pokemon{}, err
-		}
-		fmt.Printf("Received error as response with status code: %d\n", respPokemon.StatusCode)
-		return api.Pokemon{}, err
+	// the draws happen in this order (ivs stat by stat, nature, ability, moves) so a seed keeps
+	// generating the same pokemon
+	ivs := make(map[string]int, len(statCalculator.StatNames))
+	for _, stat := range statCalculator.StatNames {
+		ivs[stat] = rng.Intn(32)
 	}
-	bodyPokemon, err := io.ReadAll(respPokemon.Body)
-	respPokemon.Body.Close()
-
-	respSpecies, err := http.Get(baseSpeciesUrl + species)
+	nature := natures.All[rng.Intn(len(natures.All))].Name
+	ability := pokemonData.Abilities[rng.Intn(len(pokemonData.Abilities))].Ability.Name
+	pokemonInstance, err := newPokemon(species, pokemonData, level, nature, ability, ivs, nil)
 	if err != nil {
-		fmt.Printf("Error sending Get Request to Species Endpoint: %s\n", err.Error())
-		return api.Pokemon{}, err
-	}
-	if respSpecies.StatusCode > 299 {
-		fmt.Printf("Recieved error as response with status code: %d\n", respSpecies.StatusCode)
 		return api.Pokemon{}, err
 	}
-	bodySpecies, err := io.ReadAll(respSpecies.Body)
-	respSpecies.Body.Close()
+	moveList := CreateLearnset(species, pokemonData)
 
-	pokemonData := api.UnmarshaledPokemonInfo{}
-	speciesData := api.UnmarshaledPokemonSpecies{}
-	err = json.Unmarshal(bodyPokemon, &pokemonData)
-	if err != nil {
-		fmt.Printf("Error processing json response from Pokemon Endpoint: %s\n", err.Error())
-		return api.Pokemon{}, err
+	knowableMoves := []string{}
+
+	knowableMoves = append(knowableMoves, moveList.EggMoves...)
+	knowableMoves = append(knowableMoves, moveList.MachineMoves...)
+	knowableMoves = append(knowableMoves, moveList.TutorMoves...)
+	for i := 0; i <= level; i++ {
+		learnedAtLevel, ok := moveList.LevelUpMoves[i]
+		if ok {
+			knowableMoves = append(knowableMoves, learnedAtLevel...)
+		}
 	}
-	err = json.Unmarshal(bodySpecies, &speciesData)
+	// a move can be learned in more than one way, and some species know fewer than 4 moves
+	slices.Sort(knowableMoves)
+	knowableMoves = slices.Compact(knowableMoves)
+	numMoves := min(4, len(knowableMoves))
+	chosenMoveNames := []string{}
+	for _, index := range rng.Perm(len(knowableMoves))[:numMoves] {
+		chosenMoveNames = append(chosenMoveNames, knowableMoves[index])
+	}
+	pokemonInstance.Moves, err = loadMoves(client, chosenMoveNames)
 	if err != nil {
-		fmt.Printf("Error processing json response from Species Endpoint: %s\n", err.Error())
 		return api.Pokemon{}, err
 	}
-	pokemonData.BaseHappiness = speciesData.BaseHappiness
-	pokemonData.CaptureRate = speciesData.CaptureRate
-	pokemonData.EntryDescr = speciesData.FlavorText[0].EntryDescr
-
+	return pokemonInstance, nil
+}
+// newPokemon is a full hp pokemon of the species with the nature, ability, ivs and evs (stats left out
+// of either map are 0), its stats worked out from the species' base stats. It has no moves yet
+func newPokemon(species string, pokemonData api.UnmarshaledPokemonInfo, level int, nature, ability string, ivs, evs map[string]int) (api.Pokemon, error) {
 	stats := make(map[string]api.BundleStats)
-	for _, stat := range statNames {
-		var effort int
+	for _, stat := range statCalculator.StatNames {
+		bundle := api.BundleStats{EVValue: evs[stat], IVValue: ivs[stat]}
 		for _, statEntry := range pokemonData.BaseStats {
 			if strings.ToLower(statEntry.Stat.Name) == stat {
-				effort = statEntry.Effort
+				bundle.EffortValue = statEntry.Effort
+				bundle.BaseStat = statEntry.BaseStat
 			}
 		}
-		stats[stat] = api.BundleStats{
-			EVValue: 0,
-			IVValue: rand.Intn(32),
-			EffortValue: effort,
-		}
+		stats[stat] = bundle
 	}
-	for _, stat := range statNames {
-		if stat == "hp" {
-			hpStat := stats["hp"]
-			hpStat.StatValue = statCalculator.CalculateHp(pokemonData.BaseStats[0].BaseStat, stats["hp"].IVValue, stats["hp"].EVValue, level)
-			stats["hp"] = hpStat
-		} else {
-			var baseStatVal int
-			for _, statEntry := range pokemonData.BaseStats {
-				if strings.ToLower(statEntry.Stat.Name) == stat {
-					baseStatVal = statEntry.BaseStat
-				}
-			}
-			otherStat := stats[stat]
-			otherStat.StatValue = statCalculator.CalculateOtherStat(baseStatVal, stats[stat].IVValue, stats[stat].EVValue, level, 1)
-			// 1 is neutral nature modifier
-			stats[stat] = otherStat
-		}
-	}
-	numAbilities := len(pokemonData.Abilities)
-	
 	typeNames := make([]string, len(pokemonData.Type))
 	for i, t := range pokemonData.Type {
 		typeNames[i] = t.Type.Name
 	}
-
-	hpStat := stats["hp"]
-	pokemonInstance := api.Pokemon{	
+	pokemonInstance := api.Pokemon{
 		Species: species,
-		CurrHp: hpStat.StatValue,
 		Level: level,
 		Type: typeNames,
 		Stats: stats,
-		Nature: natures[rand.Intn(len(natures))],
-		Ability: pokemonData.Abilities[rand.Intn(numAbilities)].Ability.Name,
+		Nature: nature,
+		Ability: ability,
 		Weight: pokemonData.Weight,
+		Friendship: pokemonData.BaseHappiness,
+		Experience: experience.ForLevel(pokemonData.GrowthRate, level),
+		BaseExp: pokemonData.BaseExp,
+		GrowthRate: pokemonData.GrowthRate,
 	}
-	moveList := CreateLearnset(species, pokemonData)
-
-	knowableMoves := []string{}
+	err := statCalculator.RecalculateStats(&pokemonInstance)
+	if err != nil {
+		return api.Pokemon{}, fmt.Errorf("calculating stats of %s: %w", species, err)
+	}
+	pokemonInstance.CurrHp = pokemonInstance.Stats["hp"].StatValue
+	return pokemonInstance, nil
+}
 
-	knowableMoves = append(knowableMoves, moveList.EggMoves...)
-	knowableMoves = append(knowableMoves, moveList.MachineMoves...)
-	knowableMoves = append(knowableMoves, moveList.TutorMoves...)
-	selectedIndices := []int{}
-	for i := 0; i <= level; i++ {
-		learnedAtLevel, ok := moveList.LevelUpMoves[i]
-		if ok {
-			knowableMoves = append(knowableMoves, learnedAtLevel...)
+// loadMoves looks up the moves by name and fills the move slots in order with full pp
+func loadMoves(client *pokeapi.Client, moveNames []string) ([4]*api.MoveInstance, error) {
+	moves := [4]*api.MoveInstance{}
+	for i, moveName := range moveNames {
+		moveDetailData, err := GetMoveDetail(client, moveName)
+		if err != nil {
+			return [4]*api.MoveInstance{}, err
 		}
+		moves[i] = &api.MoveInstance{RemainingPP: moveDetailData.PP, Detail: moveDetailData}
 	}
-	for {
-		if len(selectedIndices) == 4 {
-			break
-		}
-		index := rand.Intn(len(knowableMoves))
-		if !slices.Contains(selectedIndices, index) {
-			selectedIndices = append(selectedIndices, index)
-		}
-
+	return moves, nil
+}
+// FillBaseStats looks up the species' base stats and ev yields for a pokemon saved before they were
+// recorded on its stats, so it can have its stats recalculated
+func FillBaseStats(client *pokeapi.Client, pokemon *api.Pokemon) error {
+	if !statCalculator.MissingBaseStats(pokemon) {
+		return nil
 	}
-	chosenMoveNames := []string{}
-	for i,_ := range selectedIndices {
-		chosenMoveNames = append(chosenMoveNames, knowableMoves[selectedIndices[i]])
-
+	pokemonData, err := client.PokemonWithSpecies(pokemon.Species)
+	if err != nil {
+		return fmt.Errorf("fetching pokemon data for %s: %w", pokemon.Species, err)
 	}
-	chosenMoveInstances := [4]*api.MoveInstance{}
-	for i, moveName := range chosenMoveNames {
-		moveDetailData := GetMoveDetail(moveName)
-		moveInstance := api.MoveInstance{
-			RemainingPP: moveDetailData.PP,
-			Detail: moveDetailData,
+	for _, statEntry := range pokemonData.BaseStats {
+		stat := strings.ToLower(statEntry.Stat.Name)
+		bundle, ok := pokemon.Stats[stat]
+		if !ok {
+			continue
 		}
-		chosenMoveInstances[i] = &moveInstance
+		bundle.BaseStat = statEntry.BaseStat
+		bundle.EffortValue = statEntry.Effort
+		pokemon.Stats[stat] = bundle
 	}
-	pokemonInstance.Moves = chosenMoveInstances
-
-	return pokemonInstance, nil
+	return nil
+}
+// FillGrowthData looks up the growth rate and base experience yield of a pokemon saved before they
+// were recorded and starts its experience at the beginning of its current level
+func FillGrowthData(client *pokeapi.Client, pokemon *api.Pokemon) error {
+	if pokemon.GrowthRate != "" {
+		return nil
+	}
+	pokemonData, err := client.PokemonWithSpecies(pokemon.Species)
+	if err != nil {
+		return fmt.Errorf("fetching pokemon data for %s: %w", pokemon.Species, err)
+	}
+	pokemon.GrowthRate = pokemonData.GrowthRate
+	pokemon.BaseExp = pokemonData.BaseExp
+	pokemon.Experience = max(pokemon.Experience, experience.ForLevel(pokemon.GrowthRate, pokemon.Level))
+	return nil
 }
 func CreateLearnset(species string, pokemonData api.UnmarshaledPokemonInfo) api.MoveList{
 	var versionGroups = [25]string{
@@ -276,36 +235,10 @@ func getMostRecentVersion(moveData []api.MoveData, versionGroups [25]string) str
 	}
 	return ""
 }
-func GetMoveDetail(moveName string) *api.MoveDetail {
-	moveBaseUrl := "https://pokeapi.co/api/v2/move/"
-	fullMoveUrl := moveBaseUrl + moveName
-	
-	if val, ok := moveCache[moveName]; ok {
-		return val
-	}
-
-	resp, err := http.Get(fullMoveUrl)
-	if err != nil {
-		fmt.Println("Error sending Get Request to Move endpoint: " + err.Error())
-		return &api.MoveDetail{}
-	}
-	if resp.StatusCode > 299 {
-		if resp.StatusCode == 404 {
-			fmt.Printf("%s is not a Pokemon move - please use a valid move name\n", moveName)
-		}
-		fmt.Printf("Get Request to Move endpoint returned error with status code: %d\n", resp.StatusCode)
-		return &api.MoveDetail{}
-
-	}
-	body, _ := io.ReadAll(resp.Body)
-	resp.Body.Close()
-
-	moveDetailData := api.MoveDetail{}
-	err = json.Unmarshal(body, &moveDetailData)
+func GetMoveDetail(client *pokeapi.Client, moveName string) (*api.MoveDetail, error) {
+	moveDetailData, err := client.Move(moveName)
 	if err != nil {
-		fmt.Println("Error processing json response to Move endpoint: " + err.Error())
-		return &api.MoveDetail{}
+		return nil, fmt.Errorf("fetching move detail for %s: %w", moveName, err)
 	}
-	moveCache[moveName] = &moveDetailData
-	return &moveDetailData
+	return moveDetailData, nil
 }
@@ -0,0 +1,227 @@
+package replay
+
+import (
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"os"
+	"path/filepath"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/battle"
+)
+
+// CurrentVersion is the layout version written by Save
+const CurrentVersion = 1
+
+// Replay is everything needed to play a battle again exactly as it happened: the seed, both teams
+// as they were before the first turn and every choice either side made
+type Replay struct {
+	Version        int `json:"version"`
+	Seed           int64 `json:"seed"`
+	Ai             string `json:"ai"`
+	Teams          [2][]*api.Pokemon `json:"teams"`
+	Turns          []Turn `json:"turns"`
+	playing        int // turn being replayed
+	nextChoice     int // next recorded replacement of that turn to hand out
+}
+
+// Turn is the actions both sides chose and, in order, every replacement sent in while it played out
+type Turn struct {
+	Actions        [2]battle.Action `json:"actions"`
+	Replacements   []Replacement `json:"replacements,omitempty"`
+}
+type Replacement struct {
+	Side           battle.Side `json:"side"`
+	Slot           int `json:"slot"`
+}
+
+// Streams splits a battle seed into the independent rngs used to generate the teams, to run the battle
+// and to drive the opponent's ai. A replay skips generation but still lines the battle up with the seed
+func Streams(seed int64) (generation, battleRng, aiRng *rand.Rand) {
+	seeds := rand.New(rand.NewSource(seed))
+	generation = rand.New(rand.NewSource(seeds.Int63()))
+	battleRng = rand.New(rand.NewSource(seeds.Int63()))
+	aiRng = rand.New(rand.NewSource(seeds.Int63()))
+	return generation, battleRng, aiRng
+}
+
+// New starts recording a battle. The teams are copied as they are now, so the battle that follows
+// can go on to change them
+func New(seed int64, ai string, teams [2][]*api.Pokemon) (*Replay, error) {
+	replay := &Replay{Version: CurrentVersion, Seed: seed, Ai: ai, Turns: []Turn{}}
+	for side, team := range teams {
+		snapshot, err := copyTeam(team)
+		if err != nil {
+			return nil, err
+		}
+		replay.Teams[side] = snapshot
+	}
+	return replay, nil
+}
+
+func copyTeam(team []*api.Pokemon) ([]*api.Pokemon, error) {
+	data, err := json.Marshal(team)
+	if err != nil {
+		return nil, fmt.Errorf("copying team: %w", err)
+	}
+	snapshot := []*api.Pokemon{}
+	err = json.Unmarshal(data, &snapshot)
+	if err != nil {
+		return nil, fmt.Errorf("copying team: %w", err)
+	}
+	return snapshot, nil
+}
+
+// AddTurn records the actions of the turn about to be played
+func (r *Replay) AddTurn(actions [2]battle.Action) {
+	r.Turns = append(r.Turns, Turn{Actions: actions})
+}
+
+// Record wraps a side's chooser so every replacement it picks is written into the current turn
+func (r *Replay) Record(side battle.Side, chooser battle.Chooser) battle.Chooser {
+	return func(b *battle.Battle, s battle.Side) int {
+		slot := chooser(b, s)
+		if len(r.Turns) > 0 {
+			turn := &r.Turns[len(r.Turns) - 1]
+			turn.Replacements = append(turn.Replacements, Replacement{Side: side, Slot: slot})
+		}
+		return slot
+	}
+}
+
+// Battle rebuilds the recorded battle from fresh copies of the teams, with both sides sending in the
+// replacements they picked the first time
+func (r *Replay) Battle(typeChart *api.TypeEffect) (*battle.Battle, error) {
+	var parties [2]*battle.Party
+	for side, team := range r.Teams {
+		members, err := copyTeam(team)
+		if err != nil {
+			return nil, err
+		}
+		parties[side], err = battle.NewParty(members...)
+		if err != nil {
+			return nil, fmt.Errorf("rebuilding team %d: %w", side + 1, err)
+		}
+	}
+	_, battleRng, _ := Streams(r.Seed)
+	currentBattle := battle.NewTeamBattle(parties[battle.SideUser], parties[battle.SideOpponent], typeChart, battleRng)
+	currentBattle.ChooseReplacement = [2]battle.Chooser{r.playback, r.playback}
+	return currentBattle, nil
+}
+
+// PlayTurn plays the recorded turn i on a battle made by Battle. A recorded move the pokemon on the
+// field doesn't have is an error rather than a turn played with something else
+func (r *Replay) PlayTurn(currentBattle *battle.Battle, i int) ([]battle.Event, error) {
+	if i < 0 || i >= len(r.Turns) {
+		return nil, fmt.Errorf("replay has %d turns, no turn %d", len(r.Turns), i + 1)
+	}
+	actions := r.Turns[i].Actions
+	for _, side := range []battle.Side{battle.SideUser, battle.SideOpponent} {
+		if _, forced := currentBattle.ForcedAction(side); forced || actions[side].Kind != battle.ActionFight {
+			continue
+		}
+		if battle.MoveAt(currentBattle.Pokemon[side], actions[side].MoveIndex) == nil {
+			return nil, fmt.Errorf("turn %d: side %d's %s has no move in slot %d", i + 1, side + 1, currentBattle.Pokemon[side].Species, actions[side].MoveIndex + 1)
+		}
+	}
+	r.playing, r.nextChoice = i, 0
+	return currentBattle.PlayTurn(actions), nil
+}
+
+func (r *Replay) playback(b *battle.Battle, side battle.Side) int {
+	replacements := r.Turns[r.playing].Replacements
+	if r.nextChoice >= len(replacements) || replacements[r.nextChoice].Side != side {
+		// the battle went somewhere the recording didn't, the battle falls back to its default
+		return -1
+	}
+	r.nextChoice++
+	return replacements[r.nextChoice - 1].Slot
+}
+
+// DefaultPath is where the repl writes the replay of the last battle
+func DefaultPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locating config directory: %w", err)
+	}
+	return filepath.Join(configDir, "goPokedex", "last-battle.json"), nil
+}
+
+func Save(path string, replay *Replay) error {
+	data, err := json.MarshalIndent(replay, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding replay: %w", err)
+	}
+	err = os.MkdirAll(filepath.Dir(path), 0o755)
+	if err != nil {
+		return fmt.Errorf("creating replay directory: %w", err)
+	}
+	err = os.WriteFile(path, data, 0o644)
+	if err != nil {
+		return fmt.Errorf("writing replay file: %w", err)
+	}
+	return nil
+}
+
+func Load(path string) (*Replay, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading replay file: %w", err)
+	}
+	replay := &Replay{}
+	err = json.Unmarshal(data, replay)
+	if err != nil {
+		return nil, fmt.Errorf("parsing replay file %s: %w", path, err)
+	}
+	if replay.Version != CurrentVersion {
+		return nil, fmt.Errorf("replay file %s has version %d, expected %d", path, replay.Version, CurrentVersion)
+	}
+	err = replay.validate()
+	if err != nil {
+		return nil, fmt.Errorf("replay file %s: %w", path, err)
+	}
+	return replay, nil
+}
+
+// validate checks every recorded choice points somewhere that exists, so playing the file back can't
+// index past a team or a move list
+func (r *Replay) validate() error {
+	for side, team := range r.Teams {
+		if len(team) == 0 {
+			return fmt.Errorf("team %d is empty", side + 1)
+		}
+		for slot, pokemon := range team {
+			if pokemon == nil {
+				return fmt.Errorf("team %d has no pokemon in slot %d", side + 1, slot + 1)
+			}
+		}
+	}
+	for i, turn := range r.Turns {
+		for side, action := range turn.Actions {
+			switch action.Kind {
+			case battle.ActionFight:
+				if action.MoveIndex < 0 || action.MoveIndex >= len(api.Pokemon{}.Moves) {
+					return fmt.Errorf("turn %d: side %d uses move slot %d", i + 1, side + 1, action.MoveIndex + 1)
+				}
+			case battle.ActionSwitch:
+				if action.Slot < 0 || action.Slot >= len(r.Teams[side]) {
+					return fmt.Errorf("turn %d: side %d switches to party slot %d of %d", i + 1, side + 1, action.Slot + 1, len(r.Teams[side]))
+				}
+			case battle.ActionRun, battle.ActionRecharge, battle.ActionPass:
+			default:
+				return fmt.Errorf("turn %d: side %d has unknown action %d", i + 1, side + 1, action.Kind)
+			}
+		}
+		for _, replacement := range turn.Replacements {
+			if replacement.Side != battle.SideUser && replacement.Side != battle.SideOpponent {
+				return fmt.Errorf("turn %d: replacement for unknown side %d", i + 1, replacement.Side)
+			}
+			// -1 is recorded when the chooser left the pick to the battle
+			if replacement.Slot < -1 || replacement.Slot >= len(r.Teams[replacement.Side]) {
+				return fmt.Errorf("turn %d: side %d sends in party slot %d of %d", i + 1, replacement.Side + 1, replacement.Slot + 1, len(r.Teams[replacement.Side]))
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,134 @@
+package replay
+
+import (
+	"fmt"
+	"path/filepath"
+	"testing"
+
+	"github.com/rashadat1/goPokedex/internal/ai"
+	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/battle"
+	"github.com/rashadat1/goPokedex/internal/fixtures"
+)
+
+func testTypeChart() *api.TypeEffect {
+	return &api.TypeEffect{
+		TypeMap: map[string]api.Relations{
+			"fire": {Effectiveness: map[string]float32{"grass": 2, "water": 0.5}},
+			"water": {Effectiveness: map[string]float32{"fire": 2, "grass": 0.5}},
+			"grass": {Effectiveness: map[string]float32{"water": 2, "fire": 0.5}},
+		},
+	}
+}
+func testTeams() [2][]*api.Pokemon {
+	ember := fixtures.Move("ember", "fire", "special", 40, 100)
+	waterGun := fixtures.Move("water-gun", "water", "special", 40, 100)
+	vineWhip := fixtures.Move("vine-whip", "grass", "special", 45, 100)
+	hydroPump := fixtures.Move("hydro-pump", "water", "special", 110, 80)
+	return [2][]*api.Pokemon{
+		{fixtures.Pokemon("charmander", []string{"fire"}, 120, 120, ember, waterGun), fixtures.Pokemon("squirtle", []string{"water"}, 120, 120, waterGun, hydroPump)},
+		{fixtures.Pokemon("bulbasaur", []string{"grass"}, 120, 120, vineWhip, ember), fixtures.Pokemon("psyduck", []string{"water"}, 120, 120, waterGun, hydroPump)},
+	}
+}
+
+// recordBattle plays a whole battle between two random ais and returns the replay and the event log
+func recordBattle(seed int64) (*Replay, string) {
+	teams := testTeams()
+	_, battleRng, aiRng := Streams(seed)
+	record, _ := New(seed, "random", teams)
+	userParty, _ := battle.NewParty(teams[battle.SideUser]...)
+	oppParty, _ := battle.NewParty(teams[battle.SideOpponent]...)
+	currentBattle := battle.NewTeamBattle(userParty, oppParty, testTypeChart(), battleRng)
+	strategy, _ := ai.New("random", aiRng)
+	currentBattle.ChooseReplacement[battle.SideUser] = record.Record(battle.SideUser, strategy.ChooseReplacement)
+	currentBattle.ChooseReplacement[battle.SideOpponent] = record.Record(battle.SideOpponent, strategy.ChooseReplacement)
+	log := ""
+	for !currentBattle.Over && currentBattle.Turn < 100 {
+		actions := [2]battle.Action{strategy.ChooseAction(currentBattle, battle.SideUser), strategy.ChooseAction(currentBattle, battle.SideOpponent)}
+		record.AddTurn(actions)
+		log += fmt.Sprintf("%+v\n", currentBattle.PlayTurn(actions))
+	}
+	return record, log
+}
+
+func TestReplayIsDeterministic(t *testing.T) {
+	record, recorded := recordBattle(42)
+	if len(record.Turns) == 0 {
+		t.Fatal("Got no turns expected a recorded battle")
+	}
+	if _, again := recordBattle(42); again != recorded {
+		t.Errorf("Got a different battle expected the same seed and choices to replay identically")
+	}
+
+	path := filepath.Join(t.TempDir(), "replay.json")
+	err := Save(path, record)
+	if err != nil {
+		t.Fatal(err)
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// replaying twice from the same file gives the same battle both times
+	for run := 0; run < 2; run++ {
+		currentBattle, err := loaded.Battle(testTypeChart())
+		if err != nil {
+			t.Fatal(err)
+		}
+		replayed := ""
+		for i := range loaded.Turns {
+			events, err := loaded.PlayTurn(currentBattle, i)
+			if err != nil {
+				t.Fatal(err)
+			}
+			replayed += fmt.Sprintf("%+v\n", events)
+		}
+		if replayed != recorded {
+			t.Errorf("run %d: Got replay\n%s\nexpected\n%s", run, replayed, recorded)
+		}
+		if !currentBattle.Over {
+			t.Errorf("run %d: Got a battle still going expected the replay to reach the end", run)
+		}
+	}
+}
+
+func TestLoadRejectsActionsOutsideTheTeams(t *testing.T) {
+	cases := []struct {
+		name        string
+		turn        Turn
+	}{
+		{name: "move slot", turn: Turn{Actions: [2]battle.Action{battle.Fight(4), battle.Fight(0)}}},
+		{name: "negative move slot", turn: Turn{Actions: [2]battle.Action{battle.Fight(0), battle.Fight(-1)}}},
+		{name: "switch slot", turn: Turn{Actions: [2]battle.Action{battle.Switch(2), battle.Fight(0)}}},
+		{name: "action kind", turn: Turn{Actions: [2]battle.Action{{Kind: 9}, battle.Fight(0)}}},
+		{name: "replacement slot", turn: Turn{Actions: [2]battle.Action{battle.Fight(0), battle.Fight(0)}, Replacements: []Replacement{{Side: battle.SideOpponent, Slot: 5}}}},
+		{name: "replacement side", turn: Turn{Actions: [2]battle.Action{battle.Fight(0), battle.Fight(0)}, Replacements: []Replacement{{Side: 2, Slot: 0}}}},
+	}
+	for _, c := range cases {
+		record, _ := New(1, "random", testTeams())
+		record.Turns = append(record.Turns, c.turn)
+		path := filepath.Join(t.TempDir(), "replay.json")
+		err := Save(path, record)
+		if err != nil {
+			t.Fatal(err)
+		}
+		_, err = Load(path)
+		if err == nil {
+			t.Errorf("%s: Got no error expected the replay to be rejected", c.name)
+		}
+	}
+}
+
+func TestPlayTurnRejectsAnEmptyMoveSlot(t *testing.T) {
+	record, _ := New(1, "random", testTeams())
+	// charmander only knows two moves
+	record.AddTurn([2]battle.Action{battle.Fight(3), battle.Fight(0)})
+	currentBattle, err := record.Battle(testTypeChart())
+	if err != nil {
+		t.Fatal(err)
+	}
+	events, err := record.PlayTurn(currentBattle, 0)
+	if err == nil || events != nil || currentBattle.Turn != 0 {
+		t.Errorf("Got %v after %d turns expected an error before the turn was played", err, currentBattle.Turn)
+	}
+}
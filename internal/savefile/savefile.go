@@ -0,0 +1,195 @@
+package savefile
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/box"
+	"github.com/rashadat1/goPokedex/internal/experience"
+)
+
+// CurrentVersion is the schema version written by Save - bump it and register a migration
+// from the previous version whenever the layout of SaveFile changes
+const CurrentVersion = 3
+
+type SaveFile struct {
+	Version        int `json:"version"`
+	SavedAt        time.Time `json:"saved_at"`
+	Pokedex        map[string]api.UnmarshaledPokemonInfo `json:"pokedex"`
+	Box            *box.Box `json:"box"`
+}
+
+// Migration upgrades the raw json document of a save from one version to the next
+type Migration func(doc map[string]json.RawMessage) (map[string]json.RawMessage, error)
+
+// migrations maps a version to the function that upgrades it to version+1
+var migrations = map[int]Migration{
+	1: migrateV1ToV2,
+	2: migrateV2ToV3,
+}
+
+func New() *SaveFile {
+	return &SaveFile{
+		Version: CurrentVersion,
+		Pokedex: make(map[string]api.UnmarshaledPokemonInfo),
+		Box: box.New(),
+	}
+}
+
+// DefaultPath is where the repl keeps its save between sessions
+func DefaultPath() (string, error) {
+	configDir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("locating config directory: %w", err)
+	}
+	return filepath.Join(configDir, "goPokedex", "save.json"), nil
+}
+
+// Load reads the save at path and migrates it to CurrentVersion. A missing file is reported
+// with an error wrapping fs.ErrNotExist so callers can start from New instead
+func Load(path string) (*SaveFile, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading save file: %w", err)
+	}
+	doc := make(map[string]json.RawMessage)
+	err = json.Unmarshal(data, &doc)
+	if err != nil {
+		return nil, fmt.Errorf("parsing save file %s: %w", path, err)
+	}
+	version := 0
+	if rawVersion, ok := doc["version"]; ok {
+		err = json.Unmarshal(rawVersion, &version)
+		if err != nil {
+			return nil, fmt.Errorf("parsing save file version: %w", err)
+		}
+	}
+	if version > CurrentVersion {
+		return nil, fmt.Errorf("save file version %d is newer than this pokedex supports (%d)", version, CurrentVersion)
+	}
+	for version < CurrentVersion {
+		migrate, ok := migrations[version]
+		if !ok {
+			return nil, fmt.Errorf("no migration from save file version %d", version)
+		}
+		doc, err = migrate(doc)
+		if err != nil {
+			return nil, fmt.Errorf("migrating save file from version %d: %w", version, err)
+		}
+		version++
+		doc["version"], _ = json.Marshal(version)
+	}
+
+	migrated, err := json.Marshal(doc)
+	if err != nil {
+		return nil, fmt.Errorf("re-encoding migrated save file: %w", err)
+	}
+	save := New()
+	err = json.Unmarshal(migrated, save)
+	if err != nil {
+		return nil, fmt.Errorf("parsing save file %s: %w", path, err)
+	}
+	if save.Pokedex == nil {
+		save.Pokedex = make(map[string]api.UnmarshaledPokemonInfo)
+	}
+	if save.Box == nil {
+		save.Box = box.New()
+	}
+	return save, nil
+}
+
+// version 1 only stored species entries - there are no individual pokemon to recover so the box starts empty
+func migrateV1ToV2(doc map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+	emptyBox, err := json.Marshal(box.New())
+	if err != nil {
+		return nil, err
+	}
+	doc["box"] = emptyBox
+	return doc, nil
+}
+
+// version 3 records each pokemon's base stats, ev yields, base experience and growth rate. They are
+// copied from the save's own pokedex entries, growth rates only if the entry has one - Load callers
+// look up whatever is still missing
+func migrateV2ToV3(doc map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+	savedBox := box.New()
+	if rawBox, ok := doc["box"]; ok {
+		err := json.Unmarshal(rawBox, savedBox)
+		if err != nil {
+			return nil, fmt.Errorf("parsing box: %w", err)
+		}
+	}
+	pokedex := make(map[string]api.UnmarshaledPokemonInfo)
+	if rawPokedex, ok := doc["pokedex"]; ok {
+		err := json.Unmarshal(rawPokedex, &pokedex)
+		if err != nil {
+			return nil, fmt.Errorf("parsing pokedex: %w", err)
+		}
+	}
+	for _, pokemon := range savedBox.Pokemon {
+		entry, ok := pokedex[pokemon.Species]
+		if !ok {
+			continue
+		}
+		for _, statEntry := range entry.BaseStats {
+			stat := strings.ToLower(statEntry.Stat.Name)
+			bundle, ok := pokemon.Stats[stat]
+			if !ok {
+				continue
+			}
+			bundle.BaseStat = statEntry.BaseStat
+			bundle.EffortValue = statEntry.Effort
+			pokemon.Stats[stat] = bundle
+		}
+		if pokemon.BaseExp == 0 {
+			pokemon.BaseExp = entry.BaseExp
+		}
+		if pokemon.GrowthRate == "" && entry.GrowthRate != "" {
+			pokemon.GrowthRate = entry.GrowthRate
+			pokemon.Experience = max(pokemon.Experience, experience.ForLevel(pokemon.GrowthRate, pokemon.Level))
+		}
+	}
+	rawBox, err := json.Marshal(savedBox)
+	if err != nil {
+		return nil, err
+	}
+	doc["box"] = rawBox
+	return doc, nil
+}
+
+// Save writes the save atomically (temp file + rename) so a crash mid write keeps the previous save
+func Save(path string, save *SaveFile) error {
+	save.Version = CurrentVersion
+	save.SavedAt = time.Now().UTC()
+	data, err := json.MarshalIndent(save, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encoding save file: %w", err)
+	}
+	dir := filepath.Dir(path)
+	err = os.MkdirAll(dir, 0o755)
+	if err != nil {
+		return fmt.Errorf("creating save directory %s: %w", dir, err)
+	}
+	tmp, err := os.CreateTemp(dir, "save-*.tmp")
+	if err != nil {
+		return fmt.Errorf("creating temp save file: %w", err)
+	}
+	_, err = tmp.Write(data)
+	closeErr := tmp.Close()
+	if err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		return fmt.Errorf("writing save file %s: %w", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,152 @@
+package savefile
+
+import (
+	"encoding/json"
+	"errors"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+)
+
+func TestSaveLoadRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "goPokedex", "save.json")
+	save := New()
+	save.Pokedex["pikachu"] = api.UnmarshaledPokemonInfo{BaseExp: 112, CaptureRate: 190}
+	save.Box.Deposit(api.Pokemon{Species: "pikachu", Nickname: "sparky", Level: 12, Nature: "timid"})
+	err := Save(path, save)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if loaded.Version != CurrentVersion || loaded.SavedAt.IsZero() {
+		t.Errorf("Got version %d saved at %v expected version %d with a save time", loaded.Version, loaded.SavedAt, CurrentVersion)
+	}
+	if loaded.Pokedex["pikachu"].CaptureRate != 190 {
+		t.Errorf("Got %+v expected the pikachu entry back", loaded.Pokedex)
+	}
+	pokemon, err := loaded.Box.Find("sparky")
+	if err != nil || pokemon.Id != 1 || pokemon.Level != 12 || loaded.Box.NextId != 2 {
+		t.Errorf("Got %+v (%v) and next id %d expected sparky with id 1 and next id 2", pokemon, err, loaded.Box.NextId)
+	}
+}
+
+func TestLoadRunsMigrations(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "save.json")
+	// a save from before versioning kept its species under another key
+	v0 := `{"species": {"eevee": {"base_experience": 65}}}`
+	err := os.WriteFile(path, []byte(v0), 0o644)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	_, err = Load(path)
+	if err == nil || !strings.Contains(err.Error(), "no migration from save file version 0") {
+		t.Errorf("Got %v expected a missing migration error", err)
+	}
+
+	migrations[0] = func(doc map[string]json.RawMessage) (map[string]json.RawMessage, error) {
+		doc["pokedex"] = doc["species"]
+		delete(doc, "species")
+		return doc, nil
+	}
+	t.Cleanup(func() { delete(migrations, 0) })
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if loaded.Version != CurrentVersion || loaded.Pokedex["eevee"].BaseExp != 65 {
+		t.Errorf("Got version %d with pokedex %+v expected the eevee entry at version %d", loaded.Version, loaded.Pokedex, CurrentVersion)
+	}
+}
+
+func TestMigrateV1ToV2(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "save.json")
+	// version 1 only had species entries and no box
+	v1 := `{"version": 1, "pokedex": {"bulbasaur": {"base_experience": 64}}}`
+	err := os.WriteFile(path, []byte(v1), 0o644)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if loaded.Version != CurrentVersion || loaded.Pokedex["bulbasaur"].BaseExp != 64 {
+		t.Errorf("Got version %d with pokedex %+v expected the bulbasaur entry at version %d", loaded.Version, loaded.Pokedex, CurrentVersion)
+	}
+	if loaded.Box == nil || len(loaded.Box.Pokemon) != 0 || loaded.Box.NextId != 1 {
+		t.Errorf("Got box %+v expected an empty box", loaded.Box)
+	}
+
+	doc := map[string]json.RawMessage{"version": json.RawMessage("1")}
+	doc, err = migrateV1ToV2(doc)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	if _, ok := doc["box"]; !ok {
+		t.Errorf("Got %v expected the migration to add a box", doc)
+	}
+}
+
+func TestMigrateV2ToV3(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "save.json")
+	// version 2 pokemon had no base stats, base experience or growth rate of their own
+	v2 := `{
+		"version": 2,
+		"pokedex": {
+			"pidgey": {"base_experience": 50, "GrowthRate": "medium-slow", "stats": [{"base_stat": 40, "effort": 0, "stat": {"name": "hp"}}, {"base_stat": 56, "effort": 1, "stat": {"name": "speed"}}]},
+			"onix": {"base_experience": 77, "stats": [{"base_stat": 35, "effort": 0, "stat": {"name": "hp"}}]}
+		},
+		"box": {"next_id": 3, "pokemon": [
+			{"Id": 1, "Species": "pidgey", "Level": 10, "Stats": {"hp": {"StatValue": 30}, "speed": {"StatValue": 20}}},
+			{"Id": 2, "Species": "onix", "Level": 10, "Stats": {"hp": {"StatValue": 35}}}
+		]}
+	}`
+	err := os.WriteFile(path, []byte(v2), 0o644)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	loaded, err := Load(path)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	pidgey, onix := loaded.Box.Pokemon[0], loaded.Box.Pokemon[1]
+	if pidgey.Stats["speed"].BaseStat != 56 || pidgey.Stats["speed"].EffortValue != 1 || pidgey.Stats["speed"].StatValue != 20 || pidgey.BaseExp != 50 {
+		t.Errorf("Got %+v expected pidgey's base stats and yields from the pokedex", pidgey)
+	}
+	// medium-slow needs 560 experience for level 10
+	if pidgey.GrowthRate != "medium-slow" || pidgey.Experience != 560 {
+		t.Errorf("Got growth rate %q with %d experience expected medium-slow with 560", pidgey.GrowthRate, pidgey.Experience)
+	}
+	// the growth rate is left for the caller to look up when the pokedex entry predates it
+	if onix.Stats["hp"].BaseStat != 35 || onix.BaseExp != 77 || onix.GrowthRate != "" || onix.Experience != 0 {
+		t.Errorf("Got %+v expected onix's base stats without a growth rate", onix)
+	}
+}
+
+func TestLoadRejectsNewerVersion(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "save.json")
+	newer, _ := json.Marshal(map[string]int{"version": CurrentVersion + 1})
+	err := os.WriteFile(path, newer, 0o644)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err.Error())
+	}
+	_, err = Load(path)
+	if err == nil || !strings.Contains(err.Error(), "newer than this pokedex supports") {
+		t.Errorf("Got %v expected a newer version error", err)
+	}
+}
+
+func TestLoadMissingFile(t *testing.T) {
+	_, err := Load(filepath.Join(t.TempDir(), "missing.json"))
+	if !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("Got %v expected an error wrapping fs.ErrNotExist", err)
+	}
+}
@@ -0,0 +1,87 @@
+package statCalculator
+
+import (
+	"errors"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/natures"
+)
+
+const (
+	MaxStatEVs  = 252
+	MaxTotalEVs = 510
+)
+
+var StatNames = [6]string{"hp", "attack", "defense", "special-attack", "special-defense", "speed"}
+
+var ErrMissingBaseStats = errors.New("the pokemon's base stats are not known")
+
+func TotalEVs(pokemon *api.Pokemon) int {
+	total := 0
+	for _, stat := range StatNames {
+		total += pokemon.Stats[stat].EVValue
+	}
+	return total
+}
+
+// MissingBaseStats is true for pokemon saved before base stats were recorded on them
+func MissingBaseStats(pokemon *api.Pokemon) bool {
+	for _, stat := range StatNames {
+		if pokemon.Stats[stat].BaseStat == 0 {
+			return true
+		}
+	}
+	return false
+}
+
+// AwardEVs gives the winner the ev yield of the pokemon it defeated, capped at 252 in a stat and
+// 510 overall, and returns what was actually gained per stat
+func AwardEVs(winner, defeated *api.Pokemon) map[string]int {
+	gained := make(map[string]int)
+	room := MaxTotalEVs - TotalEVs(winner)
+	for _, stat := range StatNames {
+		bundle, ok := winner.Stats[stat]
+		if !ok {
+			continue
+		}
+		gain := min(defeated.Stats[stat].EffortValue, MaxStatEVs - bundle.EVValue, room)
+		if gain <= 0 {
+			continue
+		}
+		bundle.EVValue += gain
+		winner.Stats[stat] = bundle
+		room -= gain
+		gained[stat] = gain
+	}
+	return gained
+}
+
+// ResetEVs clears every ev the pokemon has earned
+func ResetEVs(pokemon *api.Pokemon) {
+	for stat, bundle := range pokemon.Stats {
+		bundle.EVValue = 0
+		pokemon.Stats[stat] = bundle
+	}
+}
+
+// RecalculateStats works every stat out again from base stat, iv, ev, level and nature. Current hp
+// moves by as much as max hp did so a hurt pokemon stays hurt by the same amount
+func RecalculateStats(pokemon *api.Pokemon) error {
+	if MissingBaseStats(pokemon) {
+		return ErrMissingBaseStats
+	}
+	for _, stat := range StatNames {
+		bundle := pokemon.Stats[stat]
+		if stat == "hp" {
+			newHp := CalculateHp(bundle.BaseStat, bundle.IVValue, bundle.EVValue, pokemon.Level)
+			if pokemon.CurrHp > 0 {
+				pokemon.CurrHp = max(pokemon.CurrHp + newHp - bundle.StatValue, 1)
+			}
+			bundle.StatValue = newHp
+		} else {
+			bundle.StatValue = CalculateOtherStat(bundle.BaseStat, bundle.IVValue, bundle.EVValue, pokemon.Level, natures.Multiplier(pokemon.Nature, stat))
+		}
+		pokemon.Stats[stat] = bundle
+	}
+	return nil
+}
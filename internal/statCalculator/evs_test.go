@@ -0,0 +1,81 @@
+package statCalculator
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/rashadat1/goPokedex/internal/fixtures"
+)
+
+func TestAwardEVsCaps(t *testing.T) {
+	cases := []struct {
+		name            string
+		startEVs        map[string]int
+		yield           map[string]int
+		expectedGained  map[string]int
+	}{
+		{name: "plain yield", yield: map[string]int{"attack": 2}, expectedGained: map[string]int{"attack": 2}},
+		{name: "stat cap", startEVs: map[string]int{"speed": 251}, yield: map[string]int{"speed": 3}, expectedGained: map[string]int{"speed": 1}},
+		{name: "stat already capped", startEVs: map[string]int{"speed": 252}, yield: map[string]int{"speed": 3}, expectedGained: map[string]int{}},
+		{name: "total cap", startEVs: map[string]int{"attack": 252, "speed": 252, "hp": 5}, yield: map[string]int{"defense": 3}, expectedGained: map[string]int{"defense": 1}},
+	}
+	for _, c := range cases {
+		winner, defeated := fixtures.Trained("Hardy", 100), fixtures.Trained("Hardy", 100)
+		for stat, evs := range c.startEVs {
+			bundle := winner.Stats[stat]
+			bundle.EVValue = evs
+			winner.Stats[stat] = bundle
+		}
+		for stat, yield := range c.yield {
+			bundle := defeated.Stats[stat]
+			bundle.EffortValue = yield
+			defeated.Stats[stat] = bundle
+		}
+		gained := AwardEVs(winner, defeated)
+		if len(gained) != len(c.expectedGained) {
+			t.Errorf("%s: Got %v expected %v", c.name, gained, c.expectedGained)
+			continue
+		}
+		for stat, gain := range c.expectedGained {
+			if gained[stat] != gain {
+				t.Errorf("%s: Got %v expected %v", c.name, gained, c.expectedGained)
+			}
+		}
+		if TotalEVs(winner) > MaxTotalEVs {
+			t.Errorf("%s: Got %d total evs expected at most %d", c.name, TotalEVs(winner), MaxTotalEVs)
+		}
+	}
+}
+
+func TestRecalculateStats(t *testing.T) {
+	pokemon := fixtures.Trained("Adamant", 100)
+	for _, stat := range []string{"hp", "attack"} {
+		bundle := pokemon.Stats[stat]
+		bundle.EVValue = 252
+		pokemon.Stats[stat] = bundle
+	}
+	err := RecalculateStats(pokemon)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := map[string]int{"hp": 207, "attack": 167, "defense": 120, "special-attack": 108, "speed": 120}
+	for stat, value := range expected {
+		if actual := pokemon.Stats[stat].StatValue; actual != value {
+			t.Errorf("%s: Got %d expected %d", stat, actual, value)
+		}
+	}
+
+	// a hurt pokemon stays hurt by the same amount when its max hp grows
+	pokemon.CurrHp = 200
+	bundle := pokemon.Stats["hp"]
+	bundle.EVValue = 0
+	pokemon.Stats["hp"] = bundle
+	RecalculateStats(pokemon)
+	if pokemon.CurrHp != 200 - (207 - 175) {
+		t.Errorf("Got current hp %d expected %d", pokemon.CurrHp, 200 - (207 - 175))
+	}
+
+	if err := RecalculateStats(fixtures.Trained("Hardy", 0)); !errors.Is(err, ErrMissingBaseStats) {
+		t.Errorf("Got %v expected ErrMissingBaseStats", err)
+	}
+}
@@ -2,22 +2,33 @@ package main
 
 import (
 	"bufio"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"io/fs"
 	"log"
 	"math/rand"
-	"net/http"
 	"os"
+	"path/filepath"
+	"slices"
 	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/rashadat1/goPokedex/internal/ai"
 	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/box"
+	"github.com/rashadat1/goPokedex/internal/battle"
 	"github.com/rashadat1/goPokedex/internal/damageCalculator"
+	"github.com/rashadat1/goPokedex/internal/evolution"
+	"github.com/rashadat1/goPokedex/internal/experience"
+	"github.com/rashadat1/goPokedex/internal/natures"
+	"github.com/rashadat1/goPokedex/internal/pokeapi"
 	"github.com/rashadat1/goPokedex/internal/pokecache"
 	"github.com/rashadat1/goPokedex/internal/pokemonGenerator"
+	"github.com/rashadat1/goPokedex/internal/replay"
+	"github.com/rashadat1/goPokedex/internal/savefile"
+	"github.com/rashadat1/goPokedex/internal/statCalculator"
 	"github.com/rashadat1/goPokedex/internal/typeRelations"
 )
 
@@ -34,26 +45,61 @@ type config struct {
 	Next           string
 	Prev           string
 	Cache          *pokecache.Cache
+	Client         *pokeapi.Client
 	ExploreArg     string
 	CatchArg       string
+	CatchNickname  string
 	Pokedex        map[string]api.UnmarshaledPokemonInfo
+	Box            *box.Box
+	Encounters     map[string]encounterLevels // level ranges seen in the last explored area
 	InspectArg     string
+	ReleaseArg     string
 	LearnsetArg    string
 	userPokemon    string
 	oppPokemon     string
+	battleAi       string // strategy the opponent plays with, see ai.Names
+	battleSeed     *int64 // nil picks a fresh seed from Rng
+	ReplayArg      string
+	EvsArg         string
+	EvsReset       bool
+	EvolveArg      string
+	EvolveItem     string // item to use, or to hold when trading
+	Rng            *rand.Rand // the session's single source of randomness for catching and battles
+	Scanner        *bufio.Scanner // the repl's stdin scanner, prompts inside commands read from it too
+	SavePath       string
 }
 
+type encounterLevels struct {
+	Min            int
+	Max            int
+}
+
+// level used when catching a pokemon that was not seen in the last explored area
+const defaultWildLevel = 5
+
+// level battle pokemon are generated at, unless the user brings their box
+const defaultBattleLevel = 50
+
 // add struct tags so json decoder can match the Go field with the JSON field
 var userPokedex map[string]api.UnmarshaledPokemonInfo
 
 func main() {
 	inputReader := bufio.NewScanner(os.Stdin)
-	cache := pokecache.NewCache(20 * time.Second)
+	cache := newCache()
+	client := pokeapi.NewClient(cache)
+	// static game data never changes, pokemon and species pages are big so they get a bounded ttl in memory
+	cache.SetTTLPolicy(client.BaseUrl() + "type/", 0)
+	cache.SetTTLPolicy(client.BaseUrl() + "move/", 0)
+	cache.SetTTLPolicy(client.BaseUrl() + "evolution-chain/", 0)
+	cache.SetTTLPolicy(client.BaseUrl() + "pokemon/", time.Hour)
+	cache.SetTTLPolicy(client.BaseUrl() + "pokemon-species/", time.Hour)
+	cache.SetCapacity(1000)
 	userPokedex := make(map[string]api.UnmarshaledPokemonInfo)
 	configuration := config{
-		Next: "https://pokeapi.co/api/v2/location-area?offset=0&limit=20",
+		Next: client.FirstLocationAreasPage(),
 		Prev: "",
 		Cache: cache,
+		Client: client,
 		ExploreArg: "",
 		CatchArg: "",
 		InspectArg: "",
@@ -61,6 +107,25 @@ func main() {
 		userPokemon: "",
 		oppPokemon: "",
 		Pokedex: userPokedex,
+		Box: box.New(),
+		Encounters: make(map[string]encounterLevels),
+		Rng: rand.New(rand.NewSource(time.Now().UnixNano())),
+		Scanner: inputReader,
+	}
+	savePath, err := savefile.DefaultPath()
+	if err != nil {
+		log.Println("Saving is disabled: " + err.Error())
+	} else {
+		configuration.SavePath = savePath
+		err = loadSave(&configuration)
+		if err != nil && !errors.Is(err, fs.ErrNotExist) {
+			log.Println("Could not load save file: " + err.Error())
+		} else if err == nil {
+			err = backfillSpecies(&configuration)
+			if err != nil {
+				log.Println("Save loaded, but looking up species data failed for:\n" + err.Error())
+			}
+		}
 	}
 
 	commandRegistry = make(map[string]cliCommand)
@@ -91,12 +156,12 @@ func main() {
 	}
 	commandRegistry["catch"] = cliCommand{
 		name:           "catch",
-		description:    "Attempts to catch the pokemon provided as argument - adding it to the user's Pokedex",
+		description:    "Attempts to catch the pokemon provided as argument (optionally followed by a nickname) - adding it to the user's box",
 		callback:       commandCatch,
 	}
 	commandRegistry["inspect"] = cliCommand{
 		name:           "inspect",
-		description:    "Displays pokedex data for a captured pokemon",
+		description:    "Displays pokedex data for a captured species, or an owned pokemon given its id or nickname",
 		callback:       commandInspect,
 	}
 	commandRegistry["pokedex"] = cliCommand{
@@ -104,18 +169,58 @@ func main() {
 		description:    "Lists all of the pokemon that the user has caught",
 		callback:       commandPokedex,
 	}
+	commandRegistry["box"] = cliCommand{
+		name:           "box",
+		description:    "Lists every individual pokemon the user owns with its id",
+		callback:       commandBox,
+	}
+	commandRegistry["release"] = cliCommand{
+		name:           "release",
+		description:    "Releases an owned pokemon given its id or nickname, removing it from the box",
+		callback:       commandRelease,
+	}
 	commandRegistry["battle"] = cliCommand{
 		name:           "battle",
-		description:    "Starts a battle between two pokemon provided as arguments",
+		description:    "Starts a battle between two pokemon or teams (comma separated, or box for your own) provided as arguments",
 		callback:       commandBattle,
 	}
+	commandRegistry["evs"] = cliCommand{
+		name:           "evs",
+		description:    "Shows the EV spread of an owned pokemon given its id or nickname, add reset to clear it",
+		callback:       commandEvs,
+	}
+	commandRegistry["evolve"] = cliCommand{
+		name:           "evolve",
+		description:    "Evolves an owned pokemon given its id or nickname, optionally using the item given after it",
+		callback:       commandEvolve,
+	}
+	commandRegistry["trade"] = cliCommand{
+		name:           "trade",
+		description:    "Trades an owned pokemon away and back, optionally holding the item given after it, to evolve it",
+		callback:       commandTrade,
+	}
+	commandRegistry["replay"] = cliCommand{
+		name:           "replay",
+		description:    "Plays back the last battle, or the replay file given as argument, turn for turn",
+		callback:       commandReplay,
+	}
 	commandRegistry["learnset"] = cliCommand{
 		name:           "learnset",
 		description:    "Lists all of the moves that may be learned by a pokemon",
 		callback:       commandLearnset,
 	}
+	commandRegistry["save"] = cliCommand{
+		name:           "save",
+		description:    "Saves the user's Pokedex to the save file",
+		callback:       commandSave,
+	}
+	commandRegistry["load"] = cliCommand{
+		name:           "load",
+		description:    "Replaces the current Pokedex with the one in the save file",
+		callback:       commandLoad,
+	}
 	for {
-		_, err := fmt.Fprint(os.Stdout, "Pokedex > ")
+		_, err = fmt.Fprint(os.Stdout, "Pokedex > ")
 		if err != nil {
 			log.Fatal("Error starting REPL loop" + err.Error())
 		}
@@ -127,32 +232,74 @@ func main() {
 		cleanedInput := cleanInput(rawInput)
 		if len(cleanedInput) >= 1 {
 			commandName := cleanedInput[0]
-			if commandName == "explore" || commandName == "catch" || commandName == "inspect" || commandName == "learnset" {
+			if commandName == "catch" {
+				if len(cleanedInput) != 2 && len(cleanedInput) != 3 {
+					fmt.Printf("catch command takes 1 or 2 arguments %d were given\n", len(cleanedInput) - 1)
+					continue
+				}
+				configuration.CatchArg = cleanedInput[1]
+				configuration.CatchNickname = ""
+				if len(cleanedInput) == 3 {
+					configuration.CatchNickname = cleanedInput[2]
+				}
+			} else if commandName == "explore" || commandName == "inspect" || commandName == "learnset" || commandName == "release" {
 				if len(cleanedInput) != 2 {
 					fmt.Printf("%s command takes 1 argument %d\n were given", commandName, len(cleanedInput) - 1)
 					continue
 				} else {
 					if commandName == "explore" {
 						configuration.ExploreArg = cleanedInput[1]
-					} else if commandName == "catch" {
-						configuration.CatchArg = cleanedInput[1]
 					} else if commandName == "inspect" {
 						configuration.InspectArg = cleanedInput[1]
 					} else if commandName == "learnset" {
 						configuration.LearnsetArg = cleanedInput[1]
+					} else if commandName == "release" {
+						configuration.ReleaseArg = cleanedInput[1]
 					}
 				}
 			} else if commandName == "battle" {
-				if len(cleanedInput) == 3 || len(cleanedInput) == 4 {
+				configuration.userPokemon = ""
+				configuration.oppPokemon = ""
+				if len(cleanedInput) >= 3 {
 					configuration.userPokemon = cleanedInput[1]
 					configuration.oppPokemon = cleanedInput[2]
 				}
+				flags, ok := parseBattleFlags(cleanedInput[min(len(cleanedInput), 3):])
+				if !ok {
+					fmt.Println("battle only takes the --ai <strategy> and --seed <number> flags after the two pokemon")
+					continue
+				}
+				configuration.battleAi = flags.ai
+				configuration.battleSeed = flags.seed
+			} else if commandName == "evs" {
+				if len(cleanedInput) != 2 && !(len(cleanedInput) == 3 && cleanedInput[2] == "reset") {
+					fmt.Println("evs command takes a pokemon and optionally reset: evs <id or nickname> [reset]")
+					continue
+				}
+				configuration.EvsArg = cleanedInput[1]
+				configuration.EvsReset = len(cleanedInput) == 3
+			} else if commandName == "evolve" || commandName == "trade" {
+				if len(cleanedInput) != 2 && len(cleanedInput) != 3 {
+					fmt.Printf("%s command takes a pokemon and optionally an item: %s <id or nickname> [item]\n", commandName, commandName)
+					continue
+				}
+				configuration.EvolveArg = cleanedInput[1]
+				configuration.EvolveItem = ""
+				if len(cleanedInput) == 3 {
+					configuration.EvolveItem = cleanedInput[2]
+				}
+			} else if commandName == "replay" {
+				// file paths keep their case so they are read from the raw input
+				configuration.ReplayArg = ""
+				if fields := strings.Fields(rawInput); len(fields) >= 2 {
+					configuration.ReplayArg = fields[1]
+				}
 			}
 			commandData, exists := commandRegistry[commandName]
 			if exists {
 				err = commandData.callback(&configuration)
 				if err != nil {
-					fmt.Println("Error from callback: " + " from " + commandName + err.Error())
+					fmt.Println("Error from callback " + commandName + ": " + err.Error())
 				}
 			} else {
 				fmt.Println("Unknown command")
@@ -161,6 +308,20 @@ func main() {
 	}
 }
 
+func newCache() *pokecache.Cache {
+	// responses are kept on disk for a week so restarts (and offline sessions) reuse what was already fetched
+	cacheDir, err := os.UserCacheDir()
+	if err == nil {
+		var disk *pokecache.DiskStore
+		disk, err = pokecache.NewDiskStore(filepath.Join(cacheDir, "goPokedex"), 7 * 24 * time.Hour, 64 << 20)
+		if err == nil {
+			return pokecache.NewCacheWithDisk(20 * time.Second, disk)
+		}
+	}
+	log.Println("Persistent cache unavailable, using memory only: " + err.Error())
+	return pokecache.NewCache(20 * time.Second)
+}
+
 func cleanInput(text string) []string {
 	// split user input into words based on whitespace
 	// lowercase input 
@@ -177,11 +338,88 @@ func cleanInput(text string) []string {
 
 func commandExit(conf *config) error {
 	// callback for exit command
+	if conf.SavePath != "" {
+		err := writeSave(conf)
+		if err != nil {
+			fmt.Println("Could not save the Pokedex: " + err.Error())
+		} else {
+			fmt.Println("Pokedex saved!")
+		}
+	}
 	fmt.Println("Closing the Pokedex... Goodbye!")
+	conf.Cache.Close()
 	os.Exit(0)
 	return nil	
 }
 
+func commandSave(conf *config) error {
+	if conf.SavePath == "" {
+		return errors.New("no save file location is available")
+	}
+	err := writeSave(conf)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Saved %d pokemon to %s\n", len(conf.Box.Pokemon), conf.SavePath)
+	return nil
+}
+
+func commandLoad(conf *config) error {
+	if conf.SavePath == "" {
+		return errors.New("no save file location is available")
+	}
+	err := loadSave(conf)
+	if errors.Is(err, fs.ErrNotExist) {
+		fmt.Println("There is no save file yet - use save to create one")
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Loaded %d pokemon from %s\n", len(conf.Box.Pokemon), conf.SavePath)
+	err = backfillSpecies(conf)
+	if err != nil {
+		fmt.Println("Looking up species data failed for (they'll be tried again on the next load):\n" + err.Error())
+	}
+	return nil
+}
+
+func writeSave(conf *config) error {
+	save := savefile.New()
+	save.Pokedex = conf.Pokedex
+	save.Box = conf.Box
+	return savefile.Save(conf.SavePath, save)
+}
+
+// loadSave replaces the pokedex and box with the ones in the save file. It doesn't touch the network,
+// backfillSpecies does that part
+func loadSave(conf *config) error {
+	save, err := savefile.Load(conf.SavePath)
+	if err != nil {
+		return err
+	}
+	conf.Pokedex = save.Pokedex
+	conf.Box = save.Box
+	return nil
+}
+
+// backfillSpecies looks up the growth rates (and anything else the save's pokedex couldn't supply) of
+// the box pokemon. A pokemon whose lookup fails, say while offline, is left as it was and the rest are
+// still tried - the next load picks up whatever is still missing. Every failure is returned together
+func backfillSpecies(conf *config) error {
+	errs := []error{}
+	for _, pokemon := range conf.Box.Pokemon {
+		err := pokemongenerator.FillBaseStats(conf.Client, pokemon)
+		if err == nil {
+			err = pokemongenerator.FillGrowthData(conf.Client, pokemon)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", box.DisplayName(pokemon), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
 func commandHelp(conf *config) error {
 	fmt.Print("Welcome to the Pokedex!\r\nUsage:\r\n\r\n")
 	for _, value := range commandRegistry {
@@ -191,36 +429,13 @@ func commandHelp(conf *config) error {
 }
 
 func commandMap(conf *config) error {
-	urlCache := conf.Cache
-	var body []byte
 	if conf.Next == "" {
 		fmt.Println("you're on the last page")
 		return nil
 	}
-	res, ok := urlCache.Get(conf.Next)
-	
-	if ok {
-		body = res
-	} else {
-		resp, err := http.Get(conf.Next)
-		if err != nil {
-			fmt.Println("Error sending Get Request to Location-Area Endpoint: " + err.Error())
-			return nil
-		}
-		body, err = io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if resp.StatusCode > 299 {
-			fmt.Printf("Response failed with status code: %d\n", resp.StatusCode)
-			return nil
-		} else {
-			urlCache.Add(conf.Next, body)
-		}
-	}
-	locationArea := api.UnmarshaledLocationAreas{}
-	err := json.Unmarshal(body, &locationArea)
+	locationArea, err := conf.Client.LocationAreas(conf.Next)
 	if err != nil {
-		fmt.Printf("Error processing json response: %s\n", err.Error())
-		return nil
+		return err
 	}
 	conf.Next = locationArea.Next
 	conf.Prev = locationArea.Previous
@@ -231,31 +446,13 @@ func commandMap(conf *config) error {
 }
 
 func commandMapb(conf *config) error {
-	var body []byte
 	if conf.Prev == "" {
 		fmt.Println("you're on the first page")
 		return nil
 	}
-	body, ok := conf.Cache.Get(conf.Prev)
-	if !ok {
-		resp, err := http.Get(conf.Prev)
-		if err != nil {
-			fmt.Println("Error sending Get Request to Location-Area Endpoint: " + err.Error())
-		}
-		body, err = io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if resp.StatusCode > 299 {
-			fmt.Printf("Request failed with status code: %d\n", resp.StatusCode)
-			return nil
-		} else {
-			conf.Cache.Add(conf.Prev, body)
-		}
-	}
-	locationArea := api.UnmarshaledLocationAreas{}
-	err := json.Unmarshal(body, &locationArea)
+	locationArea, err := conf.Client.LocationAreas(conf.Prev)
 	if err != nil {
-		fmt.Printf("Error processing json response: %s\n", err.Error())
-		return nil
+		return err
 	}
 	conf.Next = locationArea.Next
 	conf.Prev = locationArea.Previous
@@ -265,126 +462,178 @@ func commandMapb(conf *config) error {
 	return nil
 }
 func commandExplore(conf *config) error {
-	baseUrl := "https://pokeapi.co/api/v2/location-area/"
-	areaToSearchUrl := baseUrl + conf.ExploreArg
-	var body []byte
-	res, ok := conf.Cache.Get(areaToSearchUrl)
-	if ok {
-		body = res
-	} else {
-		resp, err := http.Get(areaToSearchUrl)
-		if err != nil {
-			fmt.Println("Error sending Get Request to Location-Area Endpoint: " + err.Error())
-			return nil
-		}
-		body, err = io.ReadAll(resp.Body)
-		resp.Body.Close()
-		if resp.StatusCode > 299 {
-			fmt.Printf("Response failed with status code: %d\n", resp.StatusCode)
-			return nil
-		} else {
-			conf.Cache.Add(areaToSearchUrl, body)
-		}
+	pokemonEncounters, err := conf.Client.LocationArea(conf.ExploreArg)
+	if errors.Is(err, pokeapi.ErrNotFound) {
+		fmt.Printf("%s is not a location area - use map to list valid areas\n", conf.ExploreArg)
+		return nil
 	}
-	pokemonEncounters := api.UnmarshaledPokemonEncounters{}
-	err := json.Unmarshal(body, &pokemonEncounters)
 	if err != nil {
-		fmt.Printf("Error processing json response: %s\n", err.Error())
-		return nil
+		return err
 	}
+	conf.Encounters = make(map[string]encounterLevels)
 	for i := range pokemonEncounters.PokemonEncounters {
-		fmt.Println(pokemonEncounters.PokemonEncounters[i].Pokemon.Name)
+		encounter := pokemonEncounters.PokemonEncounters[i]
+		fmt.Println(encounter.Pokemon.Name)
+		levels := encounterLevels{}
+		for _, versionDetail := range encounter.VersionDetail {
+			for _, encounterData := range versionDetail.EncounterData {
+				if levels.Min == 0 || encounterData.MinLevel < levels.Min {
+					levels.Min = encounterData.MinLevel
+				}
+				levels.Max = max(levels.Max, encounterData.MaxLevel)
+			}
+		}
+		if levels.Min > 0 {
+			conf.Encounters[encounter.Pokemon.Name] = levels
+		}
 	}
 	return nil
 }
 func commandCatch(conf *config) error {
-	var body []byte
-	var speciesBody []byte
 	pokemonToCatch := conf.CatchArg
-	cache := conf.Cache
-
-	basePokemonUrl := "https://pokeapi.co/api/v2/pokemon/"
-	baseSpeciesUrl := "https://pokeapi.co/api/v2/pokemon-species/"
-	
-	res, ok := cache.Get(basePokemonUrl + pokemonToCatch)
-	resSpec, ok_ := cache.Get(baseSpeciesUrl + pokemonToCatch)
-	if ok && ok_ {
-		body = res
-		speciesBody = resSpec
-	} else {
-		resp, err := http.Get(basePokemonUrl + pokemonToCatch)
-		if err != nil {
-			fmt.Printf("Error sending Get Request to Pokemon Endpoint: %s\n", err.Error())
-			return nil
-		}
-		if resp.StatusCode > 299 {
-			if resp.StatusCode == 404 {
-				fmt.Printf("%s is not a Pokemon - please choose a valid Pokemon to catch\n", pokemonToCatch)
-				return nil
-			}
-			fmt.Printf("Received error as response with status code: %d\n", resp.StatusCode)
-			return nil
-		}
-		body, err = io.ReadAll(resp.Body)
-		resp.Body.Close()
 
-		respSpecies, err := http.Get(baseSpeciesUrl + pokemonToCatch)
-		if err != nil {
-			fmt.Printf("Error sending Get Request to Pokemon Endpoint: %s\n", err.Error())
-			return nil
-		}
-		if resp.StatusCode > 299 {
-			fmt.Printf("Received error as response with status code: %d\n", resp.StatusCode)
-			return nil
-		}
-		speciesBody, err = io.ReadAll(respSpecies.Body)
-		respSpecies.Body.Close()
-		// cache both endpoint responses
-		cache.Add(basePokemonUrl + pokemonToCatch, body)
-		cache.Add(baseSpeciesUrl + pokemonToCatch, speciesBody)
+	pokemonData, err := conf.Client.PokemonWithSpecies(pokemonToCatch)
+	if errors.Is(err, pokeapi.ErrNotFound) {
+		fmt.Printf("%s is not a Pokemon - please choose a valid Pokemon to catch\n", pokemonToCatch)
+		return nil
 	}
-
-	pokemonData := api.UnmarshaledPokemonInfo{}
-	pokemonSpecies := api.UnmarshaledPokemonSpecies{}
-	err := json.Unmarshal(body, &pokemonData)
 	if err != nil {
-		fmt.Printf("Error processing json response: %s\n", err.Error())
-		return nil
+		return err
 	}
-	err = json.Unmarshal(speciesBody, &pokemonSpecies)
+
+	level := defaultWildLevel
+	if levels, ok := conf.Encounters[pokemonToCatch]; ok {
+		level = levels.Min + conf.Rng.Intn(levels.Max - levels.Min + 1)
+	}
+	pokemonInstance, err := pokemongenerator.GeneratePokemon(conf.Client, pokemonToCatch, level, conf.Rng)
 	if err != nil {
-		fmt.Printf("Error processing json response: %s\n", err.Error())
-		return nil
+		return err
 	}
-	pokemonData.BaseHappiness = pokemonSpecies.BaseHappiness
-	pokemonData.CaptureRate = pokemonSpecies.CaptureRate
-	pokemonData.EntryDescr = pokemonSpecies.FlavorText[0].EntryDescr
+	pokemonInstance.Nickname = conf.CatchNickname
 
-	fmt.Printf("Throwing a Pokeball at %s...\n", pokemonToCatch)
-	if caughtPokemon(pokemonData.CaptureRate) {
+	fmt.Printf("Throwing a Pokeball at the Lvl. %d %s...\n", level, pokemonToCatch)
+	if caughtPokemon(pokemonData.CaptureRate, conf.Rng) {
 		conf.Pokedex[pokemonToCatch] = pokemonData
-		fmt.Printf("%s was caught!\n", pokemonToCatch)
-		fmt.Printf("%s's data has been added to the pokedex!\n", pokemonToCatch)
+		caught := conf.Box.Deposit(pokemonInstance)
+		fmt.Printf("%s was caught!\n", box.DisplayName(caught))
+		fmt.Printf("%s was sent to the box with id %d\n", box.DisplayName(caught), caught.Id)
 	} else {
 		fmt.Printf("%s escaped!\n", pokemonToCatch)
 	} 
 	return nil
 }
-func caughtPokemon(catchRate int) bool {
-	newSource := rand.NewSource(time.Now().UnixNano())
-	rand := rand.New(newSource)
-	randNum := rand.Intn(425) - 75
+func caughtPokemon(catchRate int, rng *rand.Rand) bool {
+	randNum := rng.Intn(425) - 75
 	if catchRate >= randNum {
 		return true
 	} else {
 		return false
 	}
 }
+func commandEvs(conf *config) error {
+	pokemon, err := conf.Box.Find(conf.EvsArg)
+	if err != nil {
+		return err
+	}
+	if conf.EvsReset {
+		statCalculator.ResetEVs(pokemon)
+		err = statCalculator.RecalculateStats(pokemon)
+		if err != nil {
+			return err
+		}
+		fmt.Printf("%s's EVs were reset\n", box.DisplayName(pokemon))
+	}
+	fmt.Printf("%s's EVs:\n", box.DisplayName(pokemon))
+	for _, stat := range statCalculator.StatNames {
+		fmt.Printf("  -%s: %d/%d (stat %d)\n", stat, pokemon.Stats[stat].EVValue, statCalculator.MaxStatEVs, pokemon.Stats[stat].StatValue)
+	}
+	fmt.Printf("Total: %d/%d\n", statCalculator.TotalEVs(pokemon), statCalculator.MaxTotalEVs)
+	return nil
+}
+// commandEvolve evolves by leveling up (friendship, time of day and known move evolutions included)
+// or, given an item, by using it
+func commandEvolve(conf *config) error {
+	attempt := evolution.Attempt{Trigger: evolution.TriggerLevelUp, TimeOfDay: evolution.TimeOfDay(time.Now())}
+	if conf.EvolveItem != "" {
+		attempt = evolution.Attempt{Trigger: evolution.TriggerUseItem, Item: conf.EvolveItem}
+	}
+	return evolveOwned(conf, attempt)
+}
+
+// commandTrade stands in for trading the pokemon away and getting it back, the only thing a trade
+// changes here is the evolutions it triggers
+func commandTrade(conf *config) error {
+	return evolveOwned(conf, evolution.Attempt{Trigger: evolution.TriggerTrade, Item: conf.EvolveItem})
+}
+func evolveOwned(conf *config, attempt evolution.Attempt) error {
+	pokemon, err := conf.Box.Find(conf.EvolveArg)
+	if err != nil {
+		return err
+	}
+	evolved, err := tryEvolve(conf, conf.Scanner, pokemon, attempt, false)
+	if err != nil || evolved {
+		return err
+	}
+	chain, err := conf.Client.EvolutionChainFor(pokemon.Species)
+	if err != nil {
+		return err
+	}
+	next := evolution.Next(chain, pokemon.Species)
+	if len(next) == 0 {
+		fmt.Printf("%s does not evolve any further\n", box.DisplayName(pokemon))
+		return nil
+	}
+	fmt.Printf("Nothing happened. %s evolves into:\n", box.DisplayName(pokemon))
+	for _, link := range next {
+		for _, detail := range link.EvolutionDetails {
+			fmt.Printf("  - %s: %s\n", link.Species.Name, evolution.Describe(detail))
+		}
+	}
+	return nil
+}
+
+// tryEvolve evolves the pokemon if the attempt meets one of its evolutions, asking first when the
+// evolution comes from leveling up in battle. Moves the new species learns on evolving are offered
+func tryEvolve(conf *config, scanner *bufio.Scanner, pokemon *api.Pokemon, attempt evolution.Attempt, confirm bool) (bool, error) {
+	chain, err := conf.Client.EvolutionChainFor(pokemon.Species)
+	if err != nil {
+		return false, err
+	}
+	species, ok := evolution.Find(chain, pokemon, attempt)
+	if !ok {
+		return false, nil
+	}
+	name := box.DisplayName(pokemon)
+	if confirm {
+		fmt.Printf("What? %s is evolving! Type stop to cancel or anything else to let it evolve\n", name)
+		if scanner.Scan() && strings.TrimSpace(scanner.Text()) == "stop" {
+			fmt.Printf("%s did not evolve\n", name)
+			return false, nil
+		}
+	}
+	pokemonData, err := conf.Client.PokemonWithSpecies(species)
+	if err != nil {
+		return false, fmt.Errorf("fetching pokemon data for %s: %w", species, err)
+	}
+	err = evolution.Evolve(pokemon, species, pokemonData)
+	if err != nil {
+		return false, err
+	}
+	fmt.Printf("Congratulations! %s evolved into %s!\n", name, species)
+	// recent games list the moves learned on evolving at level 0
+	err = learnLevelUpMoves(conf, scanner, pokemon, []int{0})
+	return true, err
+}
 func commandInspect(conf *config) error {
 	pokemonName := conf.InspectArg
 	pokemonData, ok := conf.Pokedex[pokemonName]
 	if !ok {
-		fmt.Println("you have not caught that pokemon")
+		owned, err := conf.Box.Find(pokemonName)
+		if err != nil {
+			fmt.Println("you have not caught that pokemon")
+			return nil
+		}
+		printOwnedPokemon(owned)
 		return nil
 	}
 	fmt.Println()
@@ -411,256 +660,803 @@ func commandInspect(conf *config) error {
 	}
 	fmt.Printf("Height: %v\n", pokemonData.Height)
 	fmt.Printf("Weight: %v\n", pokemonData.Weight)
+	owned := conf.Box.BySpecies(pokemonName)
+	if len(owned) > 0 {
+		fmt.Printf("Owned:\n")
+		for _, pokemon := range owned {
+			fmt.Printf("  - #%d %s Lvl. %d\n", pokemon.Id, box.DisplayName(pokemon), pokemon.Level)
+		}
+	}
 	return nil
 }
+func printOwnedPokemon(pokemon *api.Pokemon) {
+	statNames := [6]string{"hp", "attack", "defense", "special-attack", "special-defense", "speed"}
+	fmt.Println()
+	fmt.Printf("#%d %s\n", pokemon.Id, box.DisplayName(pokemon))
+	if pokemon.Nickname != "" {
+		fmt.Printf("Species: %s\n", pokemon.Species)
+	}
+	fmt.Printf("Level: %d\n", pokemon.Level)
+	fmt.Printf("Experience: %d (%d to next level)\n", pokemon.Experience, experience.ToNextLevel(pokemon))
+	fmt.Printf("HP: %d/%d\n", pokemon.CurrHp, pokemon.Stats["hp"].StatValue)
+	fmt.Printf("Nature: %s (%s)\n", pokemon.Nature, natures.Describe(pokemon.Nature))
+	fmt.Printf("Ability: %s\n", pokemon.Ability)
+	fmt.Printf("Friendship: %d\n", pokemon.Friendship)
+	fmt.Printf("Stats:\n")
+	for _, stat := range statNames {
+		// mark the stats the nature raises and lowers
+		marker := ""
+		switch multiplier := natures.Multiplier(pokemon.Nature, stat); {
+		case multiplier > 1:
+			marker = " +"
+		case multiplier < 1:
+			marker = " -"
+		}
+		fmt.Printf("  -%s: %d (IV %d, EV %d)%s\n", stat, pokemon.Stats[stat].StatValue, pokemon.Stats[stat].IVValue, pokemon.Stats[stat].EVValue, marker)
+	}
+	fmt.Printf("Moves:\n")
+	for _, move := range pokemon.Moves {
+		if move == nil {
+			continue
+		}
+		fmt.Printf("  - %s (PP: %d/%d)\n", move.Detail.Name, move.RemainingPP, move.Detail.PP)
+	}
+}
 func commandPokedex(conf *config) error {
 	fmt.Println("Your Pokedex:")
 	for pokemonName, _ := range conf.Pokedex {
-		fmt.Printf(" - %s\n", pokemonName)
+		fmt.Printf(" - %s (owned: %d)\n", pokemonName, len(conf.Box.BySpecies(pokemonName)))
+	}
+	return nil
+}
+func commandBox(conf *config) error {
+	if len(conf.Box.Pokemon) == 0 {
+		fmt.Println("Your box is empty - go catch some pokemon!")
+		return nil
+	}
+	fmt.Println("Your Box:")
+	for _, pokemon := range conf.Box.Pokemon {
+		if pokemon.Nickname != "" {
+			fmt.Printf(" #%d %s (%s) Lvl. %d\n", pokemon.Id, pokemon.Nickname, pokemon.Species, pokemon.Level)
+		} else {
+			fmt.Printf(" #%d %s Lvl. %d\n", pokemon.Id, pokemon.Species, pokemon.Level)
+		}
+	}
+	return nil
+}
+// commandRelease removes the pokemon from the box, the species stays in the pokedex
+func commandRelease(conf *config) error {
+	pokemon, err := conf.Box.Find(conf.ReleaseArg)
+	if err != nil {
+		return err
 	}
+	conf.Box.Release(pokemon.Id)
+	fmt.Printf("#%d %s was released. Bye bye, %s!\n", pokemon.Id, box.DisplayName(pokemon), box.DisplayName(pokemon))
 	return nil
 }
 func commandBattle(conf *config) error {
-	moveBaseUrlOne := "https://pokeapi.co/api/v2/move/pin-missile"
-	//moveBaseUrlTwo := "https://pokeapi.co/api/v2/move/tackle"
-
-	resp, _ := http.Get(moveBaseUrlOne)
-	body, _ := io.ReadAll(resp.Body)
-	resp.Body.Close()
+	userPokemon := conf.userPokemon
+	oppPokemon := conf.oppPokemon
+	if userPokemon == "" || oppPokemon == "" {
+		fmt.Println("battle command takes 2 arguments: battle <your pokemon> <opponent pokemon>")
+		fmt.Println("either side can be a team of up to six separated by commas, and \"box\" sends out your first six owned pokemon")
+		fmt.Printf("add --ai <strategy> to choose how the opponent plays: %s (default random)\n", strings.Join(ai.Names, ", "))
+		fmt.Println("add --seed <number> to replay the same battle")
+		return nil
+	}
 
-	moveDetailData := api.MoveDetail{}
-	_ = json.Unmarshal(body, &moveDetailData)
+	typeRelationsCache, err := typeRelations.GetTypeRelations(conf.Client)
+	if err != nil {
+		return err
+	}
+	seed := conf.Rng.Int63()
+	if conf.battleSeed != nil {
+		seed = *conf.battleSeed
+	}
+	// teams, battle and opponent each draw from their own stream of the seed, so the opponent's
+	// thinking never shifts the battle's rolls
+	generationRng, battleRng, aiRng := replay.Streams(seed)
+	userParty, owned, err := battleParty(conf, userPokemon, true, defaultBattleLevel, generationRng)
+	if err != nil {
+		return err
+	}
+	// the opponent matches the level of the team the user brings from their box
+	oppLevel := defaultBattleLevel
+	if owned != nil {
+		oppLevel = averageLevel(owned)
+	}
+	oppParty, _, err := battleParty(conf, oppPokemon, false, oppLevel, generationRng)
+	if err != nil {
+		return err
+	}
+	opponentAi, err := ai.New(conf.battleAi, aiRng)
+	if err != nil {
+		return err
+	}
+	record, err := replay.New(seed, conf.battleAi, [2][]*api.Pokemon{userParty.Members, oppParty.Members})
+	if err != nil {
+		return err
+	}
+	currentBattle := battle.NewTeamBattle(userParty, oppParty, typeRelationsCache, battleRng)
+	scanner := conf.Scanner
+	currentBattle.ChooseReplacement[battle.SideUser] = record.Record(battle.SideUser, func(b *battle.Battle, side battle.Side) int {
+		slot, ok := promptSwitch(scanner, b.Parties[side], false)
+		if !ok {
+			return -1
+		}
+		return slot
+	})
+	currentBattle.ChooseReplacement[battle.SideOpponent] = record.Record(battle.SideOpponent, opponentAi.ChooseReplacement)
 
-	fmt.Println("Pin-Missile Data:")
-	fmt.Println(moveDetailData.Meta.Ailment.Name)
-	fmt.Println(moveDetailData.Meta.CritRate)
-	fmt.Println(moveDetailData.Meta.MinHits)
-	fmt.Println(moveDetailData.Meta.MaxTurns)
+	fmt.Printf("Battle started between %s and %s! (seed %d)\n", userPokemon, oppPokemon, seed)
+	for !currentBattle.Over {
+		printBattleStatus(currentBattle)
+		// no prompt while the user's pokemon is charging, recharging or locked into a move
+		userAction, forced := currentBattle.ForcedAction(battle.SideUser)
+		if !forced {
+			var ok bool
+			userAction, ok = promptBattleAction(scanner, currentBattle.Parties[battle.SideUser])
+			if !ok {
+				// input ran out mid-battle, the turns played so far still count
+				fmt.Println("Battle abandoned")
+				break
+			}
+		}
+		oppAction := opponentAi.ChooseAction(currentBattle, battle.SideOpponent)
+		actions := [2]battle.Action{userAction, oppAction}
+		record.AddTurn(actions)
+		for _, event := range currentBattle.PlayTurn(actions) {
+			renderBattleEvent(event)
+		}
+	}
+	err = keepEVs(owned, userParty.Members)
+	if err != nil {
+		return err
+	}
+	if owned != nil && currentBattle.Over && !currentBattle.Fled && currentBattle.Winner == battle.SideUser {
+		err = awardExperience(conf, scanner, owned, experienceShares(userParty, oppParty))
+		if err != nil {
+			return err
+		}
+	}
+	replayPath, err := replay.DefaultPath()
+	if err != nil {
+		return err
+	}
+	err = replay.Save(replayPath, record)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Replay saved to %s\n", replayPath)
+	return nil
+}
 
-	userPokemon := conf.userPokemon
-	oppPokemon := conf.oppPokemon
+// commandReplay plays a recorded battle back with the same status and event output the battle had
+func commandReplay(conf *config) error {
+	replayPath := conf.ReplayArg
+	if replayPath == "" {
+		var err error
+		replayPath, err = replay.DefaultPath()
+		if err != nil {
+			return err
+		}
+	}
+	record, err := replay.Load(replayPath)
+	if err != nil {
+		return err
+	}
+	typeRelationsCache, err := typeRelations.GetTypeRelations(conf.Client)
+	if err != nil {
+		return err
+	}
+	currentBattle, err := record.Battle(typeRelationsCache)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Replaying battle with seed %d against the %s ai\n", record.Seed, record.Ai)
+	for i := range record.Turns {
+		printBattleStatus(currentBattle)
+		events, err := record.PlayTurn(currentBattle, i)
+		if err != nil {
+			return fmt.Errorf("replaying %s: %w", replayPath, err)
+		}
+		for _, event := range events {
+			renderBattleEvent(event)
+		}
+	}
+	return nil
+}
 
-	typeRelationsCache, _ := typeRelations.GetTypeRelations()
-	fmt.Println(typeRelationsCache.TypeMap["fire"])
-	
-	battleContext := api.BattleContext{
-		Rng: rand.New(rand.NewSource(time.Now().UnixNano())),
-		PokemonStates: make(map[*api.Pokemon]api.PokemonBattleState),
+// battleParty builds one side's team from a comma separated list of species generated at the level.
+// The user can send out "box" instead - the first six pokemon they own, copied so the battle
+// doesn't leave their box pokemon hurt or out of pp. Those owned pokemon are returned alongside
+func battleParty(conf *config, arg string, isUser bool, level int, rng *rand.Rand) (*battle.Party, []*api.Pokemon, error) {
+	members := []*api.Pokemon{}
+	if arg == "box" && isUser {
+		owned := []*api.Pokemon{}
+		for _, pokemon := range conf.Box.Pokemon {
+			if len(members) == battle.MaxPartySize {
+				break
+			}
+			owned = append(owned, pokemon)
+			members = append(members, battleCopy(pokemon))
+		}
+		if len(members) == 0 {
+			return nil, nil, errors.New("there are no pokemon in the box to battle with")
+		}
+		party, err := battle.NewParty(members...)
+		return party, owned, err
 	}
+	for _, species := range strings.Split(arg, ",") {
+		if species == "" {
+			continue
+		}
+		pokemonInstance, err := pokemongenerator.GeneratePokemon(conf.Client, species, level, rng)
+		if err != nil {
+			return nil, nil, fmt.Errorf("creating instance of Pokemon %s: %w", species, err)
+		}
+		members = append(members, &pokemonInstance)
+	}
+	party, err := battle.NewParty(members...)
+	return party, nil, err
+}
 
-	fmt.Printf("Battle started between %s and %s!\n", userPokemon, oppPokemon)
-		
-	userPokemonInstance, errUser := pokemongenerator.GeneratePokemon(userPokemon, 50)
-	oppPokemonInstance, errOpp := pokemongenerator.GeneratePokemon(oppPokemon, 50)
-	if errUser != nil {
-		fmt.Printf("Error creating instances of Pokemon %s: %s\n", userPokemon, errUser.Error())
+// keepEVs writes the evs the battle copies earned back onto the owned pokemon they were copied from
+func keepEVs(owned, fought []*api.Pokemon) error {
+	for i, pokemon := range owned {
+		for _, stat := range statCalculator.StatNames {
+			bundle := pokemon.Stats[stat]
+			bundle.EVValue = fought[i].Stats[stat].EVValue
+			pokemon.Stats[stat] = bundle
+		}
+		err := statCalculator.RecalculateStats(pokemon)
+		if err != nil {
+			return fmt.Errorf("updating stats of %s: %w", box.DisplayName(pokemon), err)
+		}
 	}
-	if errOpp != nil {
-		fmt.Printf("Error creating instance of Pokemon %s: %s\n", oppPokemon, errOpp.Error())
+	return nil
+}
+
+func averageLevel(team []*api.Pokemon) int {
+	total := 0
+	for _, pokemon := range team {
+		total += pokemon.Level
 	}
+	return max(total / len(team), 1)
+}
 
-	battleContext.PokemonStates[&userPokemonInstance] = api.PokemonBattleState{
-		StatStages: make(map[string]int),
+// experienceShares is the experience each of the user's party slots earned: every fainted foe's
+// yield is split evenly between the user's pokemon that were on the field and are still standing
+func experienceShares(userParty, oppParty *battle.Party) []int {
+	earners := []int{}
+	for slot, fought := range userParty.Fought {
+		if fought && userParty.Members[slot].CurrHp > 0 {
+			earners = append(earners, slot)
+		}
 	}
-	battleContext.PokemonStates[&oppPokemonInstance] = api.PokemonBattleState{
-		StatStages: make(map[string]int),
+	shares := make([]int, len(userParty.Members))
+	for _, defeated := range oppParty.Members {
+		if defeated.CurrHp > 0 {
+			continue
+		}
+		for _, slot := range earners {
+			shares[slot] += experience.Yield(defeated, len(earners))
+		}
 	}
+	return shares
+}
 
-	turnNum := 1
-	scanner := bufio.NewScanner(os.Stdin)
-	for {
-		fmt.Printf("Turn %d\n", turnNum)
-		fmt.Printf("----------------------------------\n")
-		fmt.Printf("User Pokemon:\n")
-		fmt.Printf("Lvl. %d %s\n", userPokemonInstance.Level, userPokemonInstance.Species)
-		fmt.Printf("Current HP: %d\n", userPokemonInstance.CurrHp)
-		fmt.Printf("Ability: %s\n", userPokemonInstance.Ability)
-		fmt.Printf("Nature: %s\n", userPokemonInstance.Nature)
-		fmt.Printf("----------------------------------\n")
-		fmt.Printf("Opp Pokemon:\n")
-		fmt.Printf("Lvl. %d %s\n", oppPokemonInstance.Level, oppPokemonInstance.Species)
-		fmt.Printf("Current HP: %d\n", oppPokemonInstance.CurrHp)
-		fmt.Printf("Ability: %s\n", oppPokemonInstance.Ability)
-		fmt.Printf("Nature: %s\n", oppPokemonInstance.Nature)
-			
-		userDamageDealt := damageCalculator.BasicDamageCalculator(userPokemonInstance, oppPokemonInstance, &battleContext)
-		oppDamageDealt := damageCalculator.BasicDamageCalculator(oppPokemonInstance, userPokemonInstance, &battleContext)
-		fmt.Println()
-
-		if battleContext.PokemonStates[&userPokemonInstance].ActiveMove != "" {
-			// there is an active move so we should handle it - in this case we do not let the user run or select Moves
+// awardExperience gives the owned pokemon the experience their battle copies earned, recalculating
+// the stats of every one that levels up, offering the moves it learns on the way and evolving it
+// when the new level (or the friendship it brings) is enough
+func awardExperience(conf *config, scanner *bufio.Scanner, owned []*api.Pokemon, shares []int) error {
+	for i, pokemon := range owned {
+		if shares[i] == 0 {
+			continue
+		}
+		fmt.Printf("%s gained %d experience!\n", box.DisplayName(pokemon), shares[i])
+		reached := experience.Gain(pokemon, shares[i])
+		if len(reached) == 0 {
 			continue
 		}
-		fmt.Printf("What do you want to do? run? fight?\n")
-		scanner.Scan()
-		choice := cleanInput(scanner.Text())[0]
+		err := statCalculator.RecalculateStats(pokemon)
+		if err != nil {
+			return fmt.Errorf("updating stats of %s: %w", box.DisplayName(pokemon), err)
+		}
+		fmt.Printf("%s grew to level %d!\n", box.DisplayName(pokemon), pokemon.Level)
+		for range reached {
+			evolution.LevelUpFriendship(pokemon)
+		}
+		err = learnLevelUpMoves(conf, scanner, pokemon, reached)
+		if err != nil {
+			return err
+		}
+		_, err = tryEvolve(conf, scanner, pokemon, evolution.Attempt{Trigger: evolution.TriggerLevelUp, TimeOfDay: evolution.TimeOfDay(time.Now())}, true)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
 
-		switch choice {
-		case "run":
-			fmt.Println("You got away safely!")
-			return nil
-		case "fight":
-			var moveIndexChoice int
-			for {
-				fmt.Println("Choose a move (1, 2, 3, or 4)")
-				for i, move := range userPokemonInstance.Moves {
-					fmt.Printf("%d. %s (PP: %d, Type: %s, Power: %v, Accuracy: %v)\n", i+1,
-					move.Detail.Name, move.RemainingPP, move.Detail.Type.Name,
-					move.Detail.Power, move.Detail.Accuracy)
-				}
-				scanner.Scan()
-				isValid, idx := isValidMoveChoice(userPokemonInstance, scanner.Text())
-				if isValid {
-					moveIndexChoice = idx
-					break
-				}
+// learnLevelUpMoves teaches the pokemon every move its learnset has at the levels it just reached.
+// A free move slot is filled straight away, with four moves the user picks one to forget or skips
+func learnLevelUpMoves(conf *config, scanner *bufio.Scanner, pokemon *api.Pokemon, levels []int) error {
+	pokemonData, err := conf.Client.PokemonWithSpecies(pokemon.Species)
+	if err != nil {
+		return fmt.Errorf("fetching pokemon data for %s: %w", pokemon.Species, err)
+	}
+	learnset := pokemongenerator.CreateLearnset(pokemon.Species, pokemonData)
+	for _, level := range levels {
+		for _, moveName := range learnset.LevelUpMoves[level] {
+			if knowsMove(pokemon, moveName) {
+				continue
 			}
-
-			userChosenMove := userPokemonInstance.Moves[moveIndexChoice - 1]
-			enemyChosenMove := oppPokemonInstance.Moves[battleContext.Rng.Intn(4) + 1] // for now lets just have it choose randomly
-			if userPokemonInstance.Stats["speed"].StatValue > oppPokemonInstance.Stats["speed"].StatValue {
-				damageCalculator.InitializedSpecialMove(&userPokemonInstance, &oppPokemonInstance, userChosenMove, battleContext)
-				damageCalculator.InitializedSpecialMove(&oppPokemonInstance, &userPokemonInstance, enemyChosenMove, battleContext)
-			} else {
-				damageCalculator.InitializedSpecialMove(&oppPokemonInstance, &userPokemonInstance, enemyChosenMove, battleContext)
-				damageCalculator.InitializedSpecialMove(&userPokemonInstance, &oppPokemonInstance, userChosenMove, battleContext)
+			moveDetail, err := pokemongenerator.GetMoveDetail(conf.Client, moveName)
+			if err != nil {
+				return err
+			}
+			slot := slices.Index(pokemon.Moves[:], nil)
+			if slot < 0 {
+				slot = promptForgetMove(scanner, pokemon, moveName)
+			}
+			if slot < 0 {
+				fmt.Printf("%s did not learn %s\n", box.DisplayName(pokemon), moveName)
+				continue
 			}
+			if forgotten := pokemon.Moves[slot]; forgotten != nil {
+				fmt.Printf("%s forgot %s and...\n", box.DisplayName(pokemon), forgotten.Detail.Name)
+			}
+			pokemon.Moves[slot] = &api.MoveInstance{RemainingPP: moveDetail.PP, Detail: moveDetail}
+			fmt.Printf("%s learned %s!\n", box.DisplayName(pokemon), moveName)
 		}
+	}
+	return nil
+}
+func knowsMove(pokemon *api.Pokemon, moveName string) bool {
+	for _, moveInst := range pokemon.Moves {
+		if moveInst != nil && moveInst.Detail.Name == moveName {
+			return true
+		}
+	}
+	return false
+}
 
-		switch choice {
+// promptForgetMove asks which of four known moves to give up for the new one, -1 skips learning it
+func promptForgetMove(scanner *bufio.Scanner, pokemon *api.Pokemon, moveName string) int {
+	for {
+		fmt.Printf("%s wants to learn %s but already knows four moves. Choose a move to forget (1, 2, 3, or 4) or type skip\n", box.DisplayName(pokemon), moveName)
+		for i, moveInst := range pokemon.Moves {
+			fmt.Printf("%d. %s (PP: %d, Type: %s, Power: %v)\n", i+1, moveInst.Detail.Name, moveInst.Detail.PP, moveInst.Detail.Type.Name, moveInst.Detail.Power)
+		}
+		if !scanner.Scan() {
+			return -1
+		}
+		choice := strings.TrimSpace(scanner.Text())
+		if choice == "skip" {
+			return -1
+		}
+		slot, err := strconv.Atoi(choice)
+		if err != nil || slot < 1 || slot > len(pokemon.Moves) {
+			fmt.Println("Invalid choice")
+			continue
+		}
+		return slot - 1
+	}
+}
+
+// battleCopy is a fully healed copy of an owned pokemon with its own stats and move pp
+func battleCopy(owned *api.Pokemon) *api.Pokemon {
+	pokemon := *owned
+	pokemon.CurrHp = pokemon.Stats["hp"].StatValue
+	pokemon.Stats = make(map[string]api.BundleStats, len(owned.Stats))
+	for stat, bundle := range owned.Stats {
+		pokemon.Stats[stat] = bundle
+	}
+	for i, moveInst := range owned.Moves {
+		if moveInst == nil {
+			continue
+		}
+		pokemon.Moves[i] = &api.MoveInstance{RemainingPP: moveInst.Detail.PP, Detail: moveInst.Detail}
+	}
+	return &pokemon
+}
+func printBattleStatus(currentBattle *battle.Battle) {
+	fmt.Println()
+	fmt.Printf("----------------------------------\n")
+	fmt.Printf("User Pokemon:\n")
+	printBattlePokemon(currentBattle.Pokemon[battle.SideUser])
+	fmt.Printf("----------------------------------\n")
+	fmt.Printf("Opp Pokemon:\n")
+	printBattlePokemon(currentBattle.Pokemon[battle.SideOpponent])
+	fmt.Printf("----------------------------------\n")
+	for _, side := range []battle.Side{battle.SideUser, battle.SideOpponent} {
+		if party := currentBattle.Parties[side]; len(party.Members) > 1 {
+			fmt.Printf("%s %d/%d pokemon able to battle\n", teamName(side), party.Standing(), len(party.Members))
+		}
+	}
+	if weather := currentBattle.Context.Weather; weather.Name != "" {
+		fmt.Printf("Weather: %s (%d turns left)\n", weather.Name, weather.TurnsLeft)
+	}
+	if terrain := currentBattle.Context.Terrain; terrain.Name != "" {
+		fmt.Printf("Terrain: %s (%d turns left)\n", terrain.Name, terrain.TurnsLeft)
+	}
+}
+func printBattlePokemon(pokemon *api.Pokemon) {
+	fmt.Printf("Lvl. %d %s\n", pokemon.Level, box.DisplayName(pokemon))
+	fmt.Printf("Current HP: %d/%d\n", pokemon.CurrHp, pokemon.Stats["hp"].StatValue)
+	fmt.Printf("Ability: %s\n", pokemon.Ability)
+	fmt.Printf("Nature: %s (%s)\n", pokemon.Nature, natures.Describe(pokemon.Nature))
+}
+// promptBattleAction keeps asking until the user picks a valid action, ok is false if input ran out
+func promptBattleAction(scanner *bufio.Scanner, party *battle.Party) (battle.Action, bool) {
+	userPokemonInstance := party.ActivePokemon()
+	for {
+		fmt.Printf("What do you want to do? run? fight? switch?\n")
+		if !scanner.Scan() {
+			return battle.Action{}, false
+		}
+		words := cleanInput(scanner.Text())
+		if len(words) == 0 {
+			continue
+		}
+		switch words[0] {
 		case "run":
-			fmt.Println("You ran away safely!")
-			return nil
+			return battle.Run(), true
+		case "switch":
+			if len(party.Usable()) == 0 {
+				fmt.Println("There are no other pokemon able to battle")
+				continue
+			}
+			slot, ok := promptSwitch(scanner, party, true)
+			if !ok {
+				return battle.Action{}, false
+			}
+			if slot >= 0 {
+				return battle.Switch(slot), true
+			}
 		case "fight":
-			var moveIndexChoice int
-			
+			if len(ai.LegalMoves(userPokemonInstance)) == 0 {
+				return battle.Pass(), true
+			}
 			for {
 				fmt.Println("Choose a move (1, 2, 3, or 4)")
 				for i, move := range userPokemonInstance.Moves {
+					if move == nil {
+						continue
+					}
 					fmt.Printf("%d. %s (PP: %d, Type: %s, Power: %v, Accuracy: %v)\n", i+1,
 					move.Detail.Name, move.RemainingPP, move.Detail.Type.Name,
 					move.Detail.Power, move.Detail.Accuracy)
 				}
-				scanner.Scan()
-				isValid, idx := isValidMoveChoice(userPokemonInstance, scanner.Text())
-				if isValid {
-					moveIndexChoice = idx
-					break
-				}
-			}
-			userChosenMove := userPokemonInstance.Moves[moveIndexChoice - 1]
-			fmt.Println(userChosenMove.Detail.Name)
-
-
-			if userPokemonInstance.Stats["speed"].StatValue > oppPokemonInstance.Stats["speed"].StatValue {
-				// determine which pokemon moves first
-				oppPokemonInstance.CurrHp -= userDamageDealt
-				fmt.Printf("The user's %s used \"attack\" on the foe's %s\n", userPokemonInstance.Species, oppPokemonInstance.Species)
-				if oppPokemonInstance.CurrHp <= 0 {
-					oppPokemonInstance.CurrHp = 0
-					fmt.Printf("The foe's %s has fainted\n", oppPokemonInstance.Species)
-					fmt.Println("You win!")
-					return nil
-				} else {
-					userPokemonInstance.CurrHp -= oppDamageDealt
-					fmt.Printf("The foe's %s used \"attack\" on the user's %s\n", oppPokemonInstance.Species, userPokemonInstance.Species)
-					if userPokemonInstance.CurrHp <= 0 {
-						userPokemonInstance.CurrHp = 0
-						fmt.Printf("Your %s has fained\n", userPokemonInstance.Species)
-						fmt.Println("You lose!")
-						return nil
-					}
+				if !scanner.Scan() {
+					return battle.Action{}, false
 				}
-			} else {
-				userPokemonInstance.CurrHp -= oppDamageDealt
-				fmt.Printf("The foe's %s used \"attack\" on the user's %s\n", oppPokemonInstance.Species, userPokemonInstance.Species)
-
-				if userPokemonInstance.CurrHp <= 0 {
-					userPokemonInstance.CurrHp = 0
-					fmt.Printf("Your %s has fainted\n", userPokemonInstance.Species)
-					fmt.Println("You lose!")
-					return nil
-				} else {
-					oppPokemonInstance.CurrHp -= userDamageDealt
-					fmt.Printf("The user's %s used \"attack\" on the foe's %s\n", userPokemonInstance.Species, oppPokemonInstance.Species)
-
-					if oppPokemonInstance.CurrHp <= 0 {
-						oppPokemonInstance.CurrHp = 0
-						fmt.Printf("The foe's %s has fainted\n", oppPokemonInstance.Species)
-						fmt.Println("You win!")
-						return nil
-					}
+				isValid, idx := isValidMoveChoice(*userPokemonInstance, scanner.Text())
+				if isValid {
+					return battle.Fight(idx - 1), true
 				}
 			}
-			turnNum += 1
 		default:
 			fmt.Println("Invalid choice")
-			continue
 		}
 	}
 }
-func commandLearnset(conf *config) error {
-	var body []byte
-	var speciesBody []byte
-	pokemonToListMoves := conf.LearnsetArg
-	cache := conf.Cache
 
-	basePokemonUrl := "https://pokeapi.co/api/v2/pokemon/"
-	baseSpeciesUrl := "https://pokeapi.co/api/v2/pokemon-species/"
-	
-	res, ok := cache.Get(basePokemonUrl + pokemonToListMoves)
-	resSpec, ok_ := cache.Get(baseSpeciesUrl + pokemonToListMoves)
-	if ok && ok_ {
-		body = res
-		speciesBody = resSpec
-	} else {
-		resp, err := http.Get(basePokemonUrl + pokemonToListMoves)
-		if err != nil {
-			fmt.Printf("Error sending Get Request to Pokemon Endpoint: %s\n", err.Error())
-			return nil
-		}
-		if resp.StatusCode > 299 {
-			if resp.StatusCode == 404 {
-				fmt.Printf("%s is not a Pokemon - please choose a valid Pokemon to catch\n", pokemonToListMoves)
-				return nil
+// promptSwitch asks which party member to send in. When the switch is optional "back" returns slot -1
+func promptSwitch(scanner *bufio.Scanner, party *battle.Party, optional bool) (int, bool) {
+	for {
+		fmt.Println("Choose a pokemon to send in")
+		for i, pokemon := range party.Members {
+			status := ""
+			switch {
+			case i == party.Active:
+				status = " (in battle)"
+			case pokemon.CurrHp <= 0:
+				status = " (fainted)"
 			}
-			fmt.Printf("Received error as response with status code: %d\n", resp.StatusCode)
-			return nil
+			fmt.Printf("%d. Lvl. %d %s (HP: %d/%d)%s\n", i+1, pokemon.Level, box.DisplayName(pokemon), pokemon.CurrHp, pokemon.Stats["hp"].StatValue, status)
+		}
+		if optional {
+			fmt.Println("or type back to choose another action")
+		}
+		if !scanner.Scan() {
+			return -1, false
+		}
+		choice := strings.TrimSpace(scanner.Text())
+		if optional && choice == "back" {
+			return -1, true
+		}
+		slot, err := strconv.Atoi(choice)
+		if err != nil || !party.CanSwitchTo(slot - 1) {
+			fmt.Println("Invalid choice")
+			continue
+		}
+		return slot - 1, true
+	}
+}
+func battleSideName(side battle.Side, pokemonName string) string {
+	if side == battle.SideUser {
+		return "The user's " + pokemonName
+	}
+	return "The foe's " + pokemonName
+}
+func renderBattleEvent(event battle.Event) {
+	name := battleSideName(event.Side, event.Pokemon)
+	switch event.Kind {
+	case battle.EventTurnStart:
+		fmt.Printf("Turn %d\n", event.Amount)
+	case battle.EventMove:
+		fmt.Printf("%s used %s!\n", name, event.Move)
+	case battle.EventMessage:
+		fmt.Println(event.Detail)
+	case battle.EventMiss:
+		fmt.Printf("%s's attack missed!\n", name)
+	case battle.EventNoEffect:
+		fmt.Printf("It doesn't affect %s...\n", strings.ToLower(name[:1]) + name[1:])
+	case battle.EventDamage:
+		if event.Hits > 1 {
+			fmt.Printf("Hit %d times!\n", event.Hits)
+		}
+		if event.Critical {
+			fmt.Println("A critical hit!")
+		}
+		if event.Effectiveness > 1 {
+			fmt.Println("It's super effective!")
+		} else if event.Effectiveness < 1 {
+			fmt.Println("It's not very effective...")
 		}
-		body, err = io.ReadAll(resp.Body)
-		resp.Body.Close()
+		fmt.Printf("%s took %d damage\n", name, event.Amount)
+	case battle.EventFaint:
+		fmt.Printf("%s has fainted\n", name)
+	case battle.EventRun:
+		if event.Side == battle.SideUser {
+			fmt.Println("You got away safely!")
+		} else {
+			fmt.Printf("%s fled!\n", name)
+		}
+	case battle.EventFail:
+		fmt.Println("But it failed!")
+	case battle.EventProtect:
+		fmt.Printf("%s protected itself!\n", name)
+	case battle.EventProtected:
+		if event.Detail == "psychic" {
+			fmt.Printf("%s is protected by the psychic terrain!\n", name)
+		} else {
+			fmt.Printf("%s protected itself!\n", name)
+		}
+	case battle.EventProtectionBroken:
+		fmt.Printf("%s fell for the feint!\n", name)
+	case battle.EventStatus:
+		if event.Detail == "trap" {
+			fmt.Printf("%s was trapped by %s!\n", name, event.Move)
+		} else {
+			fmt.Printf("%s %s\n", name, statusInflictedText[event.Detail])
+		}
+	case battle.EventStatusCured:
+		switch event.Detail {
+		case "sleep":
+			fmt.Printf("%s woke up!\n", name)
+		case "freeze":
+			fmt.Printf("%s thawed out!\n", name)
+		case "confusion":
+			fmt.Printf("%s snapped out of its confusion!\n", name)
+		case "trap":
+			fmt.Printf("%s was freed from %s!\n", name, event.Move)
+		default:
+			fmt.Printf("%s was cured of its %s\n", name, event.Detail)
+		}
+	case battle.EventConfused:
+		fmt.Printf("%s is confused!\n", name)
+	case battle.EventSelfHit:
+		fmt.Printf("It hurt itself in its confusion and took %d damage\n", event.Amount)
+	case battle.EventCantEscape:
+		fmt.Printf("%s is trapped and can't escape!\n", name)
+	case battle.EventRecharge:
+		fmt.Printf("%s must recharge!\n", name)
+	case battle.EventPass:
+		fmt.Printf("%s has no moves left it can use!\n", name)
+	case battle.EventHeal:
+		if event.Detail == "drain" {
+			fmt.Printf("%s had its energy drained and recovered %d HP\n", name, event.Amount)
+		} else if event.Detail == "grassy" {
+			fmt.Printf("%s's HP was restored by %d by the grassy terrain\n", name, event.Amount)
+		} else {
+			fmt.Printf("%s restored %d HP\n", name, event.Amount)
+		}
+	case battle.EventRecoil:
+		switch event.Detail {
+		case "recoil":
+			fmt.Printf("%s was damaged by the recoil and took %d damage\n", name, event.Amount)
+		case "crash":
+			fmt.Printf("%s kept going and crashed! It took %d damage\n", name, event.Amount)
+		default:
+			fmt.Printf("%s cut its own HP by %d\n", name, event.Amount)
+		}
+	case battle.EventStatChange:
+		fmt.Printf("%s's %s %s!\n", name, event.Detail, statChangeText(event.Amount))
+	case battle.EventStatLimit:
+		if event.Amount > 0 {
+			fmt.Printf("%s's %s won't go any higher!\n", name, event.Detail)
+		} else {
+			fmt.Printf("%s's %s won't go any lower!\n", name, event.Detail)
+		}
+	case battle.EventCantMove:
+		switch event.Detail {
+		case "sleep":
+			fmt.Printf("%s is fast asleep.\n", name)
+		case "freeze":
+			fmt.Printf("%s is frozen solid!\n", name)
+		case "paralysis":
+			fmt.Printf("%s is paralyzed! It can't move!\n", name)
+		case "flinch":
+			fmt.Printf("%s flinched and couldn't move!\n", name)
+		}
+	case battle.EventResidual:
+		switch event.Detail {
+		case "burn":
+			fmt.Printf("%s was hurt by its burn and took %d damage\n", name, event.Amount)
+		case "trap":
+			fmt.Printf("%s is hurt by %s and took %d damage\n", name, event.Move, event.Amount)
+		case "stealth-rock":
+			fmt.Printf("Pointed stones dug into %s for %d damage\n", strings.ToLower(name[:1]) + name[1:], event.Amount)
+		case "spikes":
+			fmt.Printf("%s is hurt by the spikes and took %d damage\n", name, event.Amount)
+		case "sandstorm", "hail":
+			fmt.Printf("%s is buffeted by the %s and took %d damage\n", name, event.Detail, event.Amount)
+		default:
+			fmt.Printf("%s was hurt by poison and took %d damage\n", name, event.Amount)
+		}
+	case battle.EventSideStart:
+		fmt.Printf("%s %s\n", teamName(event.Side), sideStartText[event.Detail])
+	case battle.EventSideEnd:
+		fmt.Printf("%s %s\n", teamName(event.Side), sideEndText[event.Detail])
+	case battle.EventFieldStart:
+		fmt.Println(fieldStartText[event.Detail])
+	case battle.EventFieldEnd:
+		fmt.Println(fieldEndText[event.Detail])
+	case battle.EventSwitchOut:
+		if event.Side == battle.SideUser {
+			fmt.Printf("%s, come back!\n", event.Pokemon)
+		} else {
+			fmt.Printf("The foe withdrew %s!\n", event.Pokemon)
+		}
+	case battle.EventSwitchIn:
+		switch {
+		case damageCalculator.MovesThatSwitchTheTargetOut[event.Move]:
+			fmt.Printf("%s was dragged out!\n", name)
+		case event.Side == battle.SideUser:
+			fmt.Printf("Go! %s!\n", event.Pokemon)
+		default:
+			fmt.Printf("The foe sent out %s!\n", event.Pokemon)
+		}
+	case battle.EventEffort:
+		// only the user's side earns evs that are kept
+		if event.Side == battle.SideUser {
+			fmt.Printf("%s gained %d %s EVs\n", event.Pokemon, event.Amount, event.Detail)
+		}
+	case battle.EventWin:
+		if event.Side == battle.SideUser {
+			fmt.Println("You win!")
+		} else {
+			fmt.Println("You lose!")
+		}
+	}
+}
+var statusInflictedText = map[string]string{
+	"burn": "was burned!",
+	"freeze": "was frozen solid!",
+	"paralysis": "is paralyzed! It may be unable to move!",
+	"poison": "was poisoned!",
+	"bad-poison": "was badly poisoned!",
+	"sleep": "fell asleep!",
+	"confusion": "became confused!",
+}
+func teamName(side battle.Side) string {
+	if side == battle.SideUser {
+		return "Your team:"
+	}
+	return "The foe's team:"
+}
+var sideStartText = map[string]string{
+	"reflect": "Reflect made it stronger against physical moves!",
+	"light-screen": "Light Screen made it stronger against special moves!",
+	"aurora-veil": "Aurora Veil made it stronger against physical and special moves!",
+	"spikes": "Spikes were scattered around its feet!",
+	"toxic-spikes": "Poison spikes were scattered around its feet!",
+	"stealth-rock": "Pointed stones float in the air around it!",
+	"sticky-web": "A sticky web has been laid out beneath it!",
+}
+var sideEndText = map[string]string{
+	"reflect": "Reflect wore off!",
+	"light-screen": "Light Screen wore off!",
+	"aurora-veil": "Aurora Veil wore off!",
+	"spikes": "The spikes disappeared from around its feet!",
+	"toxic-spikes": "The poison spikes disappeared from around its feet!",
+	"stealth-rock": "The pointed stones disappeared from around it!",
+	"sticky-web": "The sticky web has disappeared from beneath it!",
+}
+var fieldStartText = map[string]string{
+	"sun": "The sunlight turned harsh!",
+	"rain": "It started to rain!",
+	"sandstorm": "A sandstorm kicked up!",
+	"hail": "It started to hail!",
+	"snow": "It started to snow!",
+	"electric": "An electric current ran across the battlefield!",
+	"grassy": "Grass grew to cover the battlefield!",
+	"psychic": "The battlefield got weird!",
+	"misty": "Mist swirled around the battlefield!",
+}
+var fieldEndText = map[string]string{
+	"sun": "The harsh sunlight faded.",
+	"rain": "The rain stopped.",
+	"sandstorm": "The sandstorm subsided.",
+	"hail": "The hail stopped.",
+	"snow": "The snow stopped.",
+	"electric": "The electricity disappeared from the battlefield.",
+	"grassy": "The grass disappeared from the battlefield.",
+	"psychic": "The weirdness disappeared from the battlefield.",
+	"misty": "The mist disappeared from the battlefield.",
+}
+func statChangeText(stages int) string {
+	switch {
+	case stages >= 3:
+		return "rose drastically"
+	case stages == 2:
+		return "rose sharply"
+	case stages == 1:
+		return "rose"
+	case stages == -1:
+		return "fell"
+	case stages == -2:
+		return "harshly fell"
+	}
+	return "severely fell"
+}
+type battleFlags struct {
+	ai             string
+	seed           *int64
+}
 
-		respSpecies, err := http.Get(baseSpeciesUrl + pokemonToListMoves)
-		if err != nil {
-			fmt.Printf("Error sending Get Request to Pokemon Endpoint: %s\n", err.Error())
-			return nil
+// parseBattleFlags reads the optional "--ai <strategy>" and "--seed <number>" (or "--flag=value")
+// from the battle arguments
+func parseBattleFlags(args []string) (battleFlags, bool) {
+	flags := battleFlags{ai: "random"}
+	for i := 0; i < len(args); i++ {
+		name, value, hasValue := strings.Cut(args[i], "=")
+		if !hasValue {
+			if i + 1 >= len(args) {
+				return battleFlags{}, false
+			}
+			i++
+			value = args[i]
 		}
-		if resp.StatusCode > 299 {
-			fmt.Printf("Received error as response with status code: %d\n", resp.StatusCode)
-			return nil
+		switch name {
+		case "--ai":
+			flags.ai = value
+		case "--seed":
+			seed, err := strconv.ParseInt(value, 10, 64)
+			if err != nil {
+				return battleFlags{}, false
+			}
+			flags.seed = &seed
+		default:
+			return battleFlags{}, false
 		}
-		speciesBody, err = io.ReadAll(respSpecies.Body)
-		respSpecies.Body.Close()
-		// cache both endpoint responses
-		cache.Add(basePokemonUrl + pokemonToListMoves, body)
-		cache.Add(baseSpeciesUrl + pokemonToListMoves, speciesBody)
 	}
+	return flags, true
+}
+func commandLearnset(conf *config) error {
+	pokemonToListMoves := conf.LearnsetArg
 
-	pokemonData := api.UnmarshaledPokemonInfo{}
-	pokemonSpecies := api.UnmarshaledPokemonSpecies{}
-	err := json.Unmarshal(body, &pokemonData)
-	if err != nil {
-		fmt.Printf("Error processing json response: %s\n", err.Error())
+	pokemonData, err := conf.Client.Pokemon(pokemonToListMoves)
+	if errors.Is(err, pokeapi.ErrNotFound) {
+		fmt.Printf("%s is not a Pokemon - please choose a valid Pokemon\n", pokemonToListMoves)
 		return nil
 	}
-	err = json.Unmarshal(speciesBody, &pokemonSpecies)
 	if err != nil {
-		fmt.Printf("Error processing json response: %s\n", err.Error())
-		return nil
+		return err
 	}
-	pokemonData.BaseHappiness = pokemonSpecies.BaseHappiness
-	pokemonData.CaptureRate = pokemonSpecies.CaptureRate
-	pokemonData.EntryDescr = pokemonSpecies.FlavorText[0].EntryDescr	
 	
 	moveList := pokemongenerator.CreateLearnset(pokemonToListMoves, pokemonData)
 
@@ -723,10 +1519,14 @@ func isValidMoveChoice(userPokemonInstance api.Pokemon, userChoice string) (bool
 		fmt.Println("Error converting string to integer: " + err.Error())
 		return false, -999
 	}
-	if moveIndexChoice > 4 || moveIndexChoice < 0 {
+	if moveIndexChoice > 4 || moveIndexChoice < 1 {
 		fmt.Println("Please make a choice between 1 and 4")
 		return false, -999
 	}
+	if userPokemonInstance.Moves[moveIndexChoice - 1] == nil {
+		fmt.Println("There is no move in that slot")
+		return false, -999
+	}
 	if userPokemonInstance.Moves[moveIndexChoice - 1].RemainingPP == 0 {
 		fmt.Printf("%s out of PP and is unusable\n", userPokemonInstance.Moves[moveIndexChoice - 1].Detail.Name)
 		return false, -999
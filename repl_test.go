@@ -1,7 +1,15 @@
 package main
 
 import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
 	"testing"
+
+	"github.com/rashadat1/goPokedex/internal/api"
+	"github.com/rashadat1/goPokedex/internal/battle"
+	"github.com/rashadat1/goPokedex/internal/box"
+	"github.com/rashadat1/goPokedex/internal/pokeapi"
 )
 
 func TestCleanInput(t *testing.T) {
@@ -38,3 +46,84 @@ func TestCleanInput(t *testing.T) {
 	}
 }
 
+
+func TestParseBattleFlags(t *testing.T) {
+	cases := []struct {
+		args        []string
+		expectedAi  string
+		expectedSeed int64 // -1 for no seed
+		ok          bool
+	}{
+		{args: []string{}, expectedAi: "random", expectedSeed: -1, ok: true},
+		{args: []string{"--ai", "greedy"}, expectedAi: "greedy", expectedSeed: -1, ok: true},
+		{args: []string{"--ai=lookahead", "--seed", "42"}, expectedAi: "lookahead", expectedSeed: 42, ok: true},
+		{args: []string{"--seed=7"}, expectedAi: "random", expectedSeed: 7, ok: true},
+		{args: []string{"--ai"}, ok: false},
+		{args: []string{"--seed", "abc"}, ok: false},
+		{args: []string{"greedy"}, ok: false},
+	}
+	for _, c := range cases {
+		actual, ok := parseBattleFlags(c.args)
+		if ok != c.ok {
+			t.Errorf("%v: Got ok %v expected %v", c.args, ok, c.ok)
+			continue
+		}
+		if !ok {
+			continue
+		}
+		seed := int64(-1)
+		if actual.seed != nil {
+			seed = *actual.seed
+		}
+		if actual.ai != c.expectedAi || seed != c.expectedSeed {
+			t.Errorf("%v: Got %s %d expected %s %d", c.args, actual.ai, seed, c.expectedAi, c.expectedSeed)
+		}
+	}
+}
+
+func TestExperienceShares(t *testing.T) {
+	userParty := &battle.Party{
+		Members: []*api.Pokemon{{CurrHp: 10}, {CurrHp: 0}, {CurrHp: 20}, {CurrHp: 30}},
+		Fought: []bool{true, true, true, false},
+	}
+	// 64 * 21 / 7 = 192 and 112 * 14 / 7 = 224, split between the two that fought and are standing
+	oppParty := &battle.Party{
+		Members: []*api.Pokemon{{BaseExp: 64, Level: 21}, {BaseExp: 112, Level: 14}, {BaseExp: 500, Level: 50, CurrHp: 1}},
+	}
+	expected := []int{208, 0, 208, 0}
+	for i, actual := range experienceShares(userParty, oppParty) {
+		if actual != expected[i] {
+			t.Errorf("slot %d: Got %d expected %d", i, actual, expected[i])
+		}
+	}
+}
+
+func TestBackfillSpeciesCarriesOnPastFailures(t *testing.T) {
+	responses := map[string]string{
+		"/pokemon/pidgey": `{"base_experience": 50, "stats": [{"base_stat": 40, "effort": 0, "stat": {"name": "hp"}}]}`,
+		"/pokemon-species/pidgey": `{"growth_rate": {"name": "medium-slow"}}`,
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, ok := responses[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write([]byte(body))
+	}))
+	defer server.Close()
+	client := pokeapi.NewClient(nil)
+	client.SetBaseUrl(server.URL)
+	conf := &config{Client: client, Box: box.New()}
+	// the first lookup fails, the second still happens
+	conf.Box.Deposit(api.Pokemon{Species: "missingno", Level: 10, Stats: map[string]api.BundleStats{"hp": {StatValue: 30}}})
+	pidgey := conf.Box.Deposit(api.Pokemon{Species: "pidgey", Level: 10, Stats: map[string]api.BundleStats{"hp": {StatValue: 30}}})
+
+	err := backfillSpecies(conf)
+	if err == nil || !strings.Contains(err.Error(), "missingno") || strings.Contains(err.Error(), "pidgey") {
+		t.Errorf("Got %v expected only missingno's lookup to fail", err)
+	}
+	if pidgey.Stats["hp"].BaseStat != 40 || pidgey.GrowthRate != "medium-slow" || pidgey.BaseExp != 50 {
+		t.Errorf("Got %+v expected pidgey to be backfilled", pidgey)
+	}
+}